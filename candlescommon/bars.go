@@ -0,0 +1,317 @@
+package candlescommon
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	BarsTime        = "time"
+	BarsHeikinAshi  = "heikinashi"
+	BarsRenko       = "renko"
+	BarsRenkoATR    = "renkoatr"
+	BarsRange       = "range"
+	BarsVolume      = "volume"
+	BarsQuoteVolume = "quotevolume"
+)
+
+var errUnknownBarsType = errors.New("unknown bars type")
+var errWrongBarsSize = errors.New("bars size should be positive")
+
+// mergeKline adds kline data to bar, bar keeps its open values
+func mergeKline(bar *KLine, kline KLine) {
+
+	bar.CloseTime = kline.CloseTime
+	bar.ClosePrice = kline.ClosePrice
+	bar.HighPrice = math.Max(bar.HighPrice, kline.HighPrice)
+	bar.LowPrice = math.Min(bar.LowPrice, kline.LowPrice)
+
+	bar.BaseVolume += kline.BaseVolume
+	bar.QuoteVolume += kline.QuoteVolume
+	bar.TakerBuyBaseVolume += kline.TakerBuyBaseVolume
+	bar.TakerBuyQuoteVolume += kline.TakerBuyQuoteVolume
+
+	bar.Closed = kline.Closed
+}
+
+// groupKlinesUntil merges klines into bars, bar is completed when isComplete returns true
+func groupKlinesUntil(klines []KLine, isComplete func(bar KLine) bool) []KLine {
+
+	bars := make([]KLine, 0)
+
+	if len(klines) == 0 {
+		return bars
+	}
+
+	bar := KLine{}
+	started := false
+	prevClosed := klines[0].PrevCloseCandleTimestamp
+
+	for _, kline := range klines {
+
+		if !started {
+
+			bar = kline
+			bar.PrevCloseCandleTimestamp = prevClosed
+			started = true
+
+		} else {
+
+			mergeKline(&bar, kline)
+		}
+
+		if isComplete(bar) {
+
+			prevClosed = bar.CloseTime
+			bars = append(bars, bar)
+			started = false
+		}
+
+	}
+
+	//last bar isn't completed yet
+	if started {
+
+		bar.Closed = false
+		bars = append(bars, bar)
+	}
+
+	return bars
+}
+
+// HeikinAshi converts ascending klines to Heikin-Ashi candles
+func HeikinAshi(klines []KLine) []KLine {
+
+	bars := make([]KLine, 0, len(klines))
+
+	for idx, kline := range klines {
+
+		bar := kline
+
+		bar.ClosePrice = (kline.OpenPrice + kline.HighPrice + kline.LowPrice + kline.ClosePrice) / 4
+
+		if idx == 0 {
+			bar.OpenPrice = (kline.OpenPrice + kline.ClosePrice) / 2
+		} else {
+			bar.OpenPrice = (bars[idx-1].OpenPrice + bars[idx-1].ClosePrice) / 2
+		}
+
+		bar.HighPrice = math.Max(kline.HighPrice, math.Max(bar.OpenPrice, bar.ClosePrice))
+		bar.LowPrice = math.Min(kline.LowPrice, math.Min(bar.OpenPrice, bar.ClosePrice))
+
+		bars = append(bars, bar)
+	}
+
+	return bars
+}
+
+// RenkoBricks builds bricks of fixed size based on close prices, reversal needs two bricks move.
+// Bricks that were formed by the same kline split its time range, so every brick has its own open time
+func RenkoBricks(klines []KLine, brickSize float64) []KLine {
+
+	if brickSize <= 0 {
+		return make([]KLine, 0)
+	}
+
+	return renkoBricks(klines, func(idx int) float64 {
+		return brickSize
+	})
+}
+
+// renkoBricks builds bricks with size of brickSize for kline idx, the size is used for bricks formed by that kline
+func renkoBricks(klines []KLine, brickSize func(idx int) float64) []KLine {
+
+	bricks := make([]KLine, 0)
+
+	if len(klines) == 0 {
+		return bricks
+	}
+
+	top := klines[0].ClosePrice
+	bottom := klines[0].ClosePrice
+
+	prevClosed := klines[0].PrevCloseCandleTimestamp
+
+	//the earliest open time of the next brick
+	nextOpen := uint64(0)
+
+	//volume of klines that not finished any brick yet
+	pending := KLine{}
+
+	for klineIdx, kline := range klines {
+
+		size := brickSize(klineIdx)
+
+		pending.BaseVolume += kline.BaseVolume
+		pending.QuoteVolume += kline.QuoteVolume
+		pending.TakerBuyBaseVolume += kline.TakerBuyBaseVolume
+		pending.TakerBuyQuoteVolume += kline.TakerBuyQuoteVolume
+
+		klineBricks := make([]KLine, 0)
+
+		for size > 0 {
+
+			brick := KLine{Symbol: kline.Symbol, Closed: true}
+
+			if kline.ClosePrice >= top+size {
+
+				brick.OpenPrice = top
+				brick.ClosePrice = top + size
+				brick.LowPrice = brick.OpenPrice
+				brick.HighPrice = brick.ClosePrice
+
+				bottom = top
+				top += size
+
+			} else if kline.ClosePrice <= bottom-size {
+
+				brick.OpenPrice = bottom
+				brick.ClosePrice = bottom - size
+				brick.LowPrice = brick.ClosePrice
+				brick.HighPrice = brick.OpenPrice
+
+				top = bottom
+				bottom -= size
+
+			} else {
+				break
+			}
+
+			//all pending volume goes to the first brick
+			brick.BaseVolume = pending.BaseVolume
+			brick.QuoteVolume = pending.QuoteVolume
+			brick.TakerBuyBaseVolume = pending.TakerBuyBaseVolume
+			brick.TakerBuyQuoteVolume = pending.TakerBuyQuoteVolume
+
+			pending = KLine{}
+
+			klineBricks = append(klineBricks, brick)
+		}
+
+		span := kline.CloseTime - kline.OpenTime + 1
+
+		for idx := range klineBricks {
+
+			openTime := kline.OpenTime + uint64(idx)*span/uint64(len(klineBricks))
+
+			//more bricks than milliseconds in kline move to the following milliseconds
+			if openTime < nextOpen {
+				openTime = nextOpen
+			}
+
+			closeTime := kline.OpenTime + uint64(idx+1)*span/uint64(len(klineBricks)) - 1
+
+			if closeTime < openTime {
+				closeTime = openTime
+			}
+
+			klineBricks[idx].OpenTime = openTime
+			klineBricks[idx].CloseTime = closeTime
+			klineBricks[idx].PrevCloseCandleTimestamp = prevClosed
+
+			prevClosed = closeTime
+			nextOpen = closeTime + 1
+		}
+
+		bricks = append(bricks, klineBricks...)
+	}
+
+	return bricks
+}
+
+// rollingTrueRange returns Wilder ATR up to every kline, before period klines it's average of known true ranges
+func rollingTrueRange(klines []KLine, period int) []float64 {
+
+	atrs := make([]float64, 0, len(klines))
+	atr := 0.0
+
+	for idx, kline := range klines {
+
+		trueRange := kline.HighPrice - kline.LowPrice
+
+		if idx > 0 {
+			trueRange = math.Max(trueRange, math.Abs(kline.HighPrice-klines[idx-1].ClosePrice))
+			trueRange = math.Max(trueRange, math.Abs(kline.LowPrice-klines[idx-1].ClosePrice))
+		}
+
+		if idx < period {
+			atr = (float64(idx)*atr + trueRange) / float64(idx+1)
+		} else {
+			atr = (float64(period-1)*atr + trueRange) / float64(period)
+		}
+
+		atrs = append(atrs, atr)
+	}
+
+	return atrs
+}
+
+// RenkoBricksATR builds renko bricks with size equal to ATR up to the kline that forms them,
+// so bricks don't depend on klines after them
+func RenkoBricksATR(klines []KLine, atrPeriod int) []KLine {
+
+	if len(klines) == 0 || atrPeriod <= 0 {
+		return make([]KLine, 0)
+	}
+
+	atrs := rollingTrueRange(klines, atrPeriod)
+
+	return renkoBricks(klines, func(idx int) float64 {
+		return atrs[idx]
+	})
+}
+
+// RangeBars merges klines until bar high-low range reaches rangeSize
+func RangeBars(klines []KLine, rangeSize float64) []KLine {
+
+	return groupKlinesUntil(klines, func(bar KLine) bool {
+		return bar.HighPrice-bar.LowPrice >= rangeSize
+	})
+}
+
+// VolumeBars merges klines until base volume reaches volume
+func VolumeBars(klines []KLine, volume float64) []KLine {
+
+	return groupKlinesUntil(klines, func(bar KLine) bool {
+		return bar.BaseVolume >= volume
+	})
+}
+
+// QuoteVolumeBars merges klines until quote volume reaches quoteVolume
+func QuoteVolumeBars(klines []KLine, quoteVolume float64) []KLine {
+
+	return groupKlinesUntil(klines, func(bar KLine) bool {
+		return bar.QuoteVolume >= quoteVolume
+	})
+}
+
+// BuildBars converts ascending time klines to bars of barsType, size meaning depends on bars type
+func BuildBars(klines []KLine, barsType string, size float64) ([]KLine, error) {
+
+	if barsType == BarsTime || barsType == "" {
+		return klines, nil
+	}
+
+	if barsType == BarsHeikinAshi {
+		return HeikinAshi(klines), nil
+	}
+
+	if size <= 0 {
+		return nil, errWrongBarsSize
+	}
+
+	switch barsType {
+	case BarsRenko:
+		return RenkoBricks(klines, size), nil
+	case BarsRenkoATR:
+		return RenkoBricksATR(klines, int(size)), nil
+	case BarsRange:
+		return RangeBars(klines, size), nil
+	case BarsVolume:
+		return VolumeBars(klines, size), nil
+	case BarsQuoteVolume:
+		return QuoteVolumeBars(klines, size), nil
+	}
+
+	return nil, errUnknownBarsType
+}
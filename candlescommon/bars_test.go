@@ -0,0 +1,147 @@
+package candlescommon
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// pricedKLines returns chained 1m candles with open, high, low, close prices and base volume
+func pricedKLines(prices ...[5]float64) []KLine {
+
+	minutes := make([]uint64, len(prices))
+
+	for idx := range prices {
+		minutes[idx] = uint64(idx)
+	}
+
+	klines := chainedKLines(minutes...)
+
+	for idx, price := range prices {
+		klines[idx].OpenPrice, klines[idx].HighPrice, klines[idx].LowPrice, klines[idx].ClosePrice = price[0], price[1], price[2], price[3]
+		klines[idx].BaseVolume = price[4]
+	}
+
+	return klines
+}
+
+func TestHeikinAshi(t *testing.T) {
+
+	bars := HeikinAshi(pricedKLines([5]float64{10, 12, 9, 11, 1}, [5]float64{11, 13, 10, 12, 1}))
+
+	expected := [][4]float64{{10.5, 12, 9, 10.5}, {10.5, 13, 10, 11.5}}
+
+	for idx, bar := range bars {
+
+		if found := [4]float64{bar.OpenPrice, bar.HighPrice, bar.LowPrice, bar.ClosePrice}; found != expected[idx] {
+			t.Errorf("bar %d prices %v, expected %v", idx, found, expected[idx])
+		}
+	}
+}
+
+func TestRenkoBricks(t *testing.T) {
+
+	klines := pricedKLines([5]float64{10, 10, 10, 10, 1}, [5]float64{10, 11.5, 10, 11.5, 1}, [5]float64{11.5, 12.2, 11.5, 12.2, 1}, [5]float64{12.2, 12.2, 9.9, 9.9, 1})
+
+	bricks := RenkoBricks(klines, 1)
+
+	expected := []struct {
+		open, close float64
+		volume      float64
+		kline       int
+	}{
+		{10, 11, 2, 1},
+		{11, 12, 1, 2},
+		{11, 10, 1, 3},
+	}
+
+	if len(bricks) != len(expected) {
+		t.Fatalf("%d bricks, expected %d", len(bricks), len(expected))
+	}
+
+	for idx, brick := range bricks {
+
+		e := expected[idx]
+
+		if brick.OpenPrice != e.open || brick.ClosePrice != e.close || brick.BaseVolume != e.volume || brick.OpenTime != klines[e.kline].OpenTime {
+			t.Errorf("brick %d is %+v, expected %+v", idx, brick, e)
+		}
+
+		if idx > 0 && brick.PrevCloseCandleTimestamp != bricks[idx-1].CloseTime {
+			t.Errorf("brick %d isn't linked to previous brick", idx)
+		}
+	}
+
+	//kline that makes several bricks splits its time range
+	bricks = RenkoBricks(pricedKLines([5]float64{10, 10, 10, 10, 0}, [5]float64{10, 13, 10, 13, 0}), 1)
+
+	if len(bricks) != 3 || bricks[0].OpenTime != minute || bricks[1].OpenTime != minute+20000 || bricks[2].CloseTime != 2*minute-1 {
+		t.Errorf("bricks of one kline %+v", bricks)
+	}
+}
+
+// ATR bricks of the first klines don't change when later klines are added
+func TestRenkoBricksATRWithoutLookahead(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+
+	prices := make([][5]float64, 0, 300)
+	price := 100.0
+
+	for i := 0; i < cap(prices); i++ {
+
+		open := price
+		price *= math.Exp(random.NormFloat64() * 0.01)
+
+		//the last klines are much more volatile
+		if i >= 200 {
+			price *= math.Exp(random.NormFloat64() * 0.1)
+		}
+
+		prices = append(prices, [5]float64{open, math.Max(open, price) * 1.002, math.Min(open, price) * 0.998, price, 1})
+	}
+
+	klines := pricedKLines(prices...)
+
+	prefix := RenkoBricksATR(klines[:200], 14)
+	full := RenkoBricksATR(klines, 14)
+
+	if len(prefix) == 0 || len(full) < len(prefix) || !reflect.DeepEqual(full[:len(prefix)], prefix) {
+		t.Fatalf("bricks of the first klines are changed by the next klines")
+	}
+}
+
+func TestRangeAndVolumeBars(t *testing.T) {
+
+	klines := pricedKLines([5]float64{10, 12, 9, 11, 1}, [5]float64{11, 13, 10, 12, 2}, [5]float64{12, 12.5, 11.5, 12, 3})
+
+	bars := RangeBars(klines, 4)
+
+	if len(bars) != 2 || bars[0].HighPrice != 13 || bars[0].LowPrice != 9 || bars[0].CloseTime != klines[1].CloseTime || !bars[0].Closed || bars[1].Closed {
+		t.Errorf("range bars %+v", bars)
+	}
+
+	bars = VolumeBars(klines, 3)
+
+	if len(bars) != 2 || bars[0].BaseVolume != 3 || bars[1].BaseVolume != 3 || bars[1].PrevCloseCandleTimestamp != bars[0].CloseTime || !bars[1].Closed {
+		t.Errorf("volume bars %+v", bars)
+	}
+}
+
+func TestBuildBars(t *testing.T) {
+
+	klines := pricedKLines([5]float64{10, 12, 9, 11, 1})
+
+	if bars, err := BuildBars(klines, BarsTime, 0); err != nil || !reflect.DeepEqual(bars, klines) {
+		t.Errorf("time bars %+v, %v", bars, err)
+	}
+
+	if _, err := BuildBars(klines, BarsRenko, 0); err != errWrongBarsSize {
+		t.Errorf("renko without size returned %v", err)
+	}
+
+	if _, err := BuildBars(klines, "kagi", 1); err != errUnknownBarsType {
+		t.Errorf("unknown bars returned %v", err)
+	}
+}
@@ -98,6 +98,14 @@ func CheckCandles(klines []KLine) bool {
 	return true
 }
 func MinutesGroupKlineDesc(klines []KLine, minutes uint64, includeLastKline bool, includeFirstKline bool) []KLine {
+	return groupKlineDesc(klines, minutes*60*1000, includeLastKline, includeFirstKline)
+}
+
+func SecondsGroupKlineDesc(klines []KLine, seconds uint64, includeLastKline bool, includeFirstKline bool) []KLine {
+	return groupKlineDesc(klines, seconds*1000, includeLastKline, includeFirstKline)
+}
+
+func groupKlineDesc(klines []KLine, duration uint64, includeLastKline bool, includeFirstKline bool) []KLine {
 
 	//grouped klines
 	groupedKlines := make([]KLine, 0)
@@ -111,13 +119,13 @@ func MinutesGroupKlineDesc(klines []KLine, minutes uint64, includeLastKline bool
 	index := len(klines) - 1
 
 	//if first kline in array isn't have start open time iterating...
-	if klines[index].OpenTime%(minutes*60*1000) != 0 && klines[index].PrevCloseCandleTimestamp != 0 && !includeFirstKline {
+	if klines[index].OpenTime%duration != 0 && klines[index].PrevCloseCandleTimestamp != 0 && !includeFirstKline {
 
-		division := klines[index].OpenTime / (minutes * 60 * 1000)
+		division := klines[index].OpenTime / duration
 
 		for ; index >= 0; index-- {
 
-			newDivision := klines[index].OpenTime / (minutes * 60 * 1000)
+			newDivision := klines[index].OpenTime / duration
 
 			//find next open time start...
 			if newDivision > division {
@@ -135,7 +143,7 @@ func MinutesGroupKlineDesc(klines []KLine, minutes uint64, includeLastKline bool
 	//iterate over next values
 	for ; index >= 0; index-- {
 
-		newDivision := klines[index].OpenTime / (minutes * 60 * 1000)
+		newDivision := klines[index].OpenTime / duration
 
 		//if we find, that divisor have been increased, we should create new Kline
 		if newDivision > division {
@@ -178,7 +186,7 @@ func MinutesGroupKlineDesc(klines []KLine, minutes uint64, includeLastKline bool
 	//we should handle two situations,when we should also prepend a kline
 	//first: last candle is not closed
 	//second: last original kline completes the new kline, in this situation we should check their close time
-	if currentKline.Closed == false || (includeLastKline && len(groupedKlines) > 0) || (currentKline.OpenTime > 0 && currentKline.PrevCloseCandleTimestamp == 0) || (currentKline.CloseTime == currentKline.OpenTime+minutes*60*1000-1) {
+	if currentKline.Closed == false || (includeLastKline && len(groupedKlines) > 0) || (currentKline.OpenTime > 0 && currentKline.PrevCloseCandleTimestamp == 0) || (currentKline.CloseTime == currentKline.OpenTime+duration-1) {
 
 		//prepend item
 		groupedKlines = append(groupedKlines, currentKline)
@@ -0,0 +1,382 @@
+package patterns
+
+import (
+	"github.com/NERON/tran/candlescommon"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	BullishEngulfing = "bullish engulfing"
+	BearishEngulfing = "bearish engulfing"
+	Hammer           = "hammer"
+	Doji             = "doji"
+	MorningStar      = "morning star"
+	EveningStar      = "evening star"
+	InsideBar        = "inside bar"
+	OutsideBar       = "outside bar"
+	BullishPinBar    = "bullish pin bar"
+	BearishPinBar    = "bearish pin bar"
+)
+
+const (
+	DirectionBearish = -1
+	DirectionNeutral = 0
+	DirectionBullish = 1
+)
+
+// Pattern is recognized pattern that ends on candle with Index,
+// Confidence is from 0 to 1 and shows how clear the pattern is
+type Pattern struct {
+	Name       string
+	Index      int
+	OpenTime   uint64
+	Candles    int
+	Direction  int
+	Confidence float64
+}
+
+type recognizer func(klines []candlescommon.KLine, idx int) (Pattern, bool)
+
+var recognizers = map[string]recognizer{
+	BullishEngulfing: bullishEngulfing,
+	BearishEngulfing: bearishEngulfing,
+	Hammer:           hammer,
+	Doji:             doji,
+	MorningStar:      morningStar,
+	EveningStar:      eveningStar,
+	InsideBar:        insideBar,
+	OutsideBar:       outsideBar,
+	BullishPinBar:    bullishPinBar,
+	BearishPinBar:    bearishPinBar,
+}
+
+// Names returns names of all recognized patterns
+func Names() []string {
+
+	names := make([]string, 0, len(recognizers))
+
+	for name := range recognizers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseNames parses comma separated pattern names, empty string is all patterns
+func ParseNames(description string) []string {
+
+	if description == "" {
+		return Names()
+	}
+
+	names := make([]string, 0)
+
+	for _, name := range strings.Split(description, ",") {
+
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if _, ok := recognizers[name]; ok {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// Recognize returns patterns from names that end on candle idx
+func Recognize(klines []candlescommon.KLine, idx int, names []string, minConfidence float64) []Pattern {
+
+	found := make([]Pattern, 0)
+
+	if idx < 0 || idx >= len(klines) {
+		return found
+	}
+
+	for _, name := range names {
+
+		recognize, ok := recognizers[name]
+
+		if !ok {
+			continue
+		}
+
+		pattern, ok := recognize(klines, idx)
+
+		if ok && pattern.Confidence >= minConfidence {
+			found = append(found, pattern)
+		}
+	}
+
+	return found
+}
+
+// FindPatterns returns patterns from names over all candles
+func FindPatterns(klines []candlescommon.KLine, names []string, minConfidence float64) []Pattern {
+
+	found := make([]Pattern, 0)
+
+	for idx := range klines {
+		found = append(found, Recognize(klines, idx, names, minConfidence)...)
+	}
+
+	return found
+}
+
+// HasPattern returns the most confident pattern from names that ends on candle idx
+func HasPattern(klines []candlescommon.KLine, idx int, names []string, minConfidence float64) (Pattern, bool) {
+
+	best := Pattern{}
+	ok := false
+
+	for _, pattern := range Recognize(klines, idx, names, minConfidence) {
+
+		if !ok || pattern.Confidence > best.Confidence {
+			best = pattern
+			ok = true
+		}
+	}
+
+	return best, ok
+}
+
+// ConfirmsPivot returns true if pattern from names ends on pivot candle idx or on the next candle.
+// Pattern on the next candle is a lookahead of one candle, pivot can be accepted only after the next candle is closed
+func ConfirmsPivot(klines []candlescommon.KLine, idx int, names []string, minConfidence float64) bool {
+
+	if _, ok := HasPattern(klines, idx, names, minConfidence); ok {
+		return true
+	}
+
+	_, ok := HasPattern(klines, idx+1, names, minConfidence)
+
+	return ok
+}
+
+func body(kline candlescommon.KLine) float64 {
+	return math.Abs(kline.ClosePrice - kline.OpenPrice)
+}
+
+func candleRange(kline candlescommon.KLine) float64 {
+	return kline.HighPrice - kline.LowPrice
+}
+
+func upperShadow(kline candlescommon.KLine) float64 {
+	return kline.HighPrice - math.Max(kline.OpenPrice, kline.ClosePrice)
+}
+
+func lowerShadow(kline candlescommon.KLine) float64 {
+	return math.Min(kline.OpenPrice, kline.ClosePrice) - kline.LowPrice
+}
+
+func isBullish(kline candlescommon.KLine) bool {
+	return kline.ClosePrice > kline.OpenPrice
+}
+
+func isBearish(kline candlescommon.KLine) bool {
+	return kline.ClosePrice < kline.OpenPrice
+}
+
+func direction(kline candlescommon.KLine) int {
+
+	if isBullish(kline) {
+		return DirectionBullish
+	}
+
+	if isBearish(kline) {
+		return DirectionBearish
+	}
+
+	return DirectionNeutral
+}
+
+func clamp(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
+
+func newPattern(klines []candlescommon.KLine, idx int, name string, candles int, dir int, confidence float64) Pattern {
+	return Pattern{Name: name, Index: idx, OpenTime: klines[idx].OpenTime, Candles: candles, Direction: dir, Confidence: clamp(confidence)}
+}
+
+// engulfing is body of candle that covers opposite body of previous candle,
+// confidence grows with size of engulfing body relative to engulfed one
+func engulfing(klines []candlescommon.KLine, idx int, bullish bool) (Pattern, bool) {
+
+	if idx < 1 {
+		return Pattern{}, false
+	}
+
+	prev := klines[idx-1]
+	cur := klines[idx]
+
+	if bullish && (!isBearish(prev) || !isBullish(cur) || cur.OpenPrice > prev.ClosePrice || cur.ClosePrice < prev.OpenPrice) {
+		return Pattern{}, false
+	}
+
+	if !bullish && (!isBullish(prev) || !isBearish(cur) || cur.OpenPrice < prev.ClosePrice || cur.ClosePrice > prev.OpenPrice) {
+		return Pattern{}, false
+	}
+
+	confidence := 0.5 + 0.5*(1-body(prev)/body(cur))
+
+	if bullish {
+		return newPattern(klines, idx, BullishEngulfing, 2, DirectionBullish, confidence), true
+	}
+
+	return newPattern(klines, idx, BearishEngulfing, 2, DirectionBearish, confidence), true
+}
+
+func bullishEngulfing(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+	return engulfing(klines, idx, true)
+}
+
+func bearishEngulfing(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+	return engulfing(klines, idx, false)
+}
+
+// hammer has small body at the top and lower shadow at least twice longer than body
+func hammer(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+
+	cur := klines[idx]
+	fullRange := candleRange(cur)
+
+	if fullRange == 0 || lowerShadow(cur) < 2*body(cur) || upperShadow(cur) > 0.25*fullRange {
+		return Pattern{}, false
+	}
+
+	confidence := lowerShadow(cur)/fullRange - upperShadow(cur)/fullRange
+
+	return newPattern(klines, idx, Hammer, 1, DirectionBullish, confidence), true
+}
+
+// doji has body not larger than tenth of range
+func doji(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+
+	cur := klines[idx]
+	fullRange := candleRange(cur)
+
+	if fullRange == 0 || body(cur) > 0.1*fullRange {
+		return Pattern{}, false
+	}
+
+	return newPattern(klines, idx, Doji, 1, DirectionNeutral, 1-body(cur)/fullRange/0.1), true
+}
+
+// star is long candle, small candle and opposite candle that closes beyond middle of the first body,
+// confidence is how deep the third candle closes into the first body
+func star(klines []candlescommon.KLine, idx int, bullish bool) (Pattern, bool) {
+
+	if idx < 2 {
+		return Pattern{}, false
+	}
+
+	first := klines[idx-2]
+	second := klines[idx-1]
+	third := klines[idx]
+
+	if candleRange(first) == 0 || body(first) < 0.5*candleRange(first) || body(second) > 0.3*body(first) {
+		return Pattern{}, false
+	}
+
+	middle := (first.OpenPrice + first.ClosePrice) / 2
+	secondMiddle := (second.OpenPrice + second.ClosePrice) / 2
+
+	if bullish {
+
+		if !isBearish(first) || !isBullish(third) || secondMiddle > first.ClosePrice || third.ClosePrice <= middle {
+			return Pattern{}, false
+		}
+
+		return newPattern(klines, idx, MorningStar, 3, DirectionBullish, (third.ClosePrice-first.ClosePrice)/(first.OpenPrice-first.ClosePrice)), true
+	}
+
+	if !isBullish(first) || !isBearish(third) || secondMiddle < first.ClosePrice || third.ClosePrice >= middle {
+		return Pattern{}, false
+	}
+
+	return newPattern(klines, idx, EveningStar, 3, DirectionBearish, (first.ClosePrice-third.ClosePrice)/(first.ClosePrice-first.OpenPrice)), true
+}
+
+func morningStar(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+	return star(klines, idx, true)
+}
+
+func eveningStar(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+	return star(klines, idx, false)
+}
+
+// insideBar is candle inside range of previous candle, smaller candle is more confident
+func insideBar(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+
+	if idx < 1 {
+		return Pattern{}, false
+	}
+
+	prev := klines[idx-1]
+	cur := klines[idx]
+
+	if candleRange(prev) == 0 || cur.HighPrice > prev.HighPrice || cur.LowPrice < prev.LowPrice {
+		return Pattern{}, false
+	}
+
+	return newPattern(klines, idx, InsideBar, 2, direction(cur), 1-candleRange(cur)/candleRange(prev)), true
+}
+
+// outsideBar is candle that covers range of previous candle from both sides, larger candle is more confident
+func outsideBar(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+
+	if idx < 1 {
+		return Pattern{}, false
+	}
+
+	prev := klines[idx-1]
+	cur := klines[idx]
+
+	if cur.HighPrice <= prev.HighPrice || cur.LowPrice >= prev.LowPrice {
+		return Pattern{}, false
+	}
+
+	return newPattern(klines, idx, OutsideBar, 2, direction(cur), 1-candleRange(prev)/candleRange(cur)), true
+}
+
+// pinBar has shadow of at least two thirds of range that sticks out of previous candle
+func pinBar(klines []candlescommon.KLine, idx int, bullish bool) (Pattern, bool) {
+
+	if idx < 1 {
+		return Pattern{}, false
+	}
+
+	prev := klines[idx-1]
+	cur := klines[idx]
+	fullRange := candleRange(cur)
+
+	if fullRange == 0 {
+		return Pattern{}, false
+	}
+
+	if bullish {
+
+		if lowerShadow(cur) < 2*fullRange/3 || cur.LowPrice >= prev.LowPrice {
+			return Pattern{}, false
+		}
+
+		return newPattern(klines, idx, BullishPinBar, 2, DirectionBullish, 0.5+1.5*(lowerShadow(cur)/fullRange-2.0/3)), true
+	}
+
+	if upperShadow(cur) < 2*fullRange/3 || cur.HighPrice <= prev.HighPrice {
+		return Pattern{}, false
+	}
+
+	return newPattern(klines, idx, BearishPinBar, 2, DirectionBearish, 0.5+1.5*(upperShadow(cur)/fullRange-2.0/3)), true
+}
+
+func bullishPinBar(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+	return pinBar(klines, idx, true)
+}
+
+func bearishPinBar(klines []candlescommon.KLine, idx int) (Pattern, bool) {
+	return pinBar(klines, idx, false)
+}
@@ -0,0 +1,191 @@
+package patterns
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/NERON/tran/candlescommon"
+)
+
+func kline(open float64, close float64, high float64, low float64) candlescommon.KLine {
+	return candlescommon.KLine{OpenPrice: open, ClosePrice: close, HighPrice: high, LowPrice: low}
+}
+
+func TestRecognizers(t *testing.T) {
+
+	cases := []struct {
+		name       string
+		klines     []candlescommon.KLine
+		direction  int
+		confidence float64
+
+		//the same pattern with broken rule isn't recognized
+		broken []candlescommon.KLine
+	}{
+		{
+			name:       BullishEngulfing,
+			klines:     []candlescommon.KLine{kline(10, 9, 10.1, 8.9), kline(8.9, 10.5, 10.6, 8.8)},
+			direction:  DirectionBullish,
+			confidence: 0.5 + 0.5*(1-1/1.6),
+			broken:     []candlescommon.KLine{kline(10, 9, 10.1, 8.9), kline(8.9, 9.9, 10, 8.8)},
+		},
+		{
+			name:       BearishEngulfing,
+			klines:     []candlescommon.KLine{kline(9, 10, 10.1, 8.9), kline(10.1, 8.5, 10.2, 8.4)},
+			direction:  DirectionBearish,
+			confidence: 0.5 + 0.5*(1-1/1.6),
+			broken:     []candlescommon.KLine{kline(9, 10, 10.1, 8.9), kline(10.1, 9.1, 10.2, 9)},
+		},
+		{
+			name:       Hammer,
+			klines:     []candlescommon.KLine{kline(9.8, 10, 10.05, 9)},
+			direction:  DirectionBullish,
+			confidence: 0.8/1.05 - 0.05/1.05,
+			broken:     []candlescommon.KLine{kline(9.8, 10, 10.5, 9)},
+		},
+		{
+			name:       Doji,
+			klines:     []candlescommon.KLine{kline(10, 10.02, 10.5, 9.5)},
+			direction:  DirectionNeutral,
+			confidence: 0.8,
+			broken:     []candlescommon.KLine{kline(10, 10.2, 10.5, 9.5)},
+		},
+		{
+			name:       MorningStar,
+			klines:     []candlescommon.KLine{kline(11, 10, 11.1, 9.9), kline(9.8, 9.85, 9.9, 9.7), kline(9.9, 10.8, 10.9, 9.8)},
+			direction:  DirectionBullish,
+			confidence: 0.8,
+			broken:     []candlescommon.KLine{kline(11, 10, 11.1, 9.9), kline(9.8, 9.85, 9.9, 9.7), kline(9.9, 10.4, 10.5, 9.8)},
+		},
+		{
+			name:       EveningStar,
+			klines:     []candlescommon.KLine{kline(10, 11, 11.1, 9.9), kline(11.2, 11.15, 11.3, 11.1), kline(11.1, 10.2, 11.2, 10.1)},
+			direction:  DirectionBearish,
+			confidence: 0.8,
+			broken:     []candlescommon.KLine{kline(10, 11, 11.1, 9.9), kline(11.2, 11.15, 11.3, 11.1), kline(11.1, 10.6, 11.2, 10.5)},
+		},
+		{
+			name:       InsideBar,
+			klines:     []candlescommon.KLine{kline(9.5, 10.5, 11, 9), kline(9.6, 10.4, 10.5, 9.5)},
+			direction:  DirectionBullish,
+			confidence: 0.5,
+			broken:     []candlescommon.KLine{kline(9.5, 10.5, 11, 9), kline(9.6, 10.4, 11.5, 9.5)},
+		},
+		{
+			name:       OutsideBar,
+			klines:     []candlescommon.KLine{kline(9.6, 10.4, 10.5, 9.5), kline(10.8, 9.2, 11, 9)},
+			direction:  DirectionBearish,
+			confidence: 0.5,
+			broken:     []candlescommon.KLine{kline(9.6, 10.4, 10.5, 9.5), kline(10.8, 9.6, 11, 9.6)},
+		},
+		{
+			name:       BullishPinBar,
+			klines:     []candlescommon.KLine{kline(10, 10.2, 10.5, 9.5), kline(10.3, 10.4, 10.5, 9)},
+			direction:  DirectionBullish,
+			confidence: 0.8,
+			broken:     []candlescommon.KLine{kline(10, 10.2, 10.5, 8.5), kline(10.3, 10.4, 10.5, 9)},
+		},
+		{
+			name:       BearishPinBar,
+			klines:     []candlescommon.KLine{kline(10, 10.2, 10.5, 9.5), kline(9.7, 9.6, 11, 9.5)},
+			direction:  DirectionBearish,
+			confidence: 0.8,
+			broken:     []candlescommon.KLine{kline(10, 10.2, 11.5, 9.5), kline(9.7, 9.6, 11, 9.5)},
+		},
+	}
+
+	if len(cases) != len(Names()) {
+		t.Fatalf("%d recognizers are tested of %d", len(cases), len(Names()))
+	}
+
+	for _, c := range cases {
+
+		idx := len(c.klines) - 1
+
+		pattern, ok := HasPattern(c.klines, idx, []string{c.name}, 0)
+
+		if !ok {
+			t.Errorf("%s isn't recognized", c.name)
+			continue
+		}
+
+		if pattern.Name != c.name || pattern.Index != idx || pattern.Candles != len(c.klines) || pattern.Direction != c.direction {
+			t.Errorf("%s is recognized as %+v", c.name, pattern)
+		}
+
+		if math.Abs(pattern.Confidence-c.confidence) > 1e-9 {
+			t.Errorf("%s confidence %f, expected %f", c.name, pattern.Confidence, c.confidence)
+		}
+
+		if _, ok := HasPattern(c.klines, idx, []string{c.name}, c.confidence+0.01); ok {
+			t.Errorf("%s is recognized with confidence above its own", c.name)
+		}
+
+		if _, ok := HasPattern(c.broken, idx, []string{c.name}, 0); ok {
+			t.Errorf("%s is recognized with broken rule", c.name)
+		}
+
+		//patterns that need previous candles aren't recognized without them
+		if len(c.klines) > 1 {
+
+			if _, ok := HasPattern(c.klines[idx:], 0, []string{c.name}, 0); ok {
+				t.Errorf("%s is recognized without previous candles", c.name)
+			}
+		}
+	}
+}
+
+func TestConfidenceRange(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+
+	klines := make([]candlescommon.KLine, 0, 5000)
+	price := 100.0
+
+	for i := 0; i < cap(klines); i++ {
+
+		open := price
+		price *= math.Exp(random.NormFloat64() * 0.01)
+
+		high := math.Max(open, price) * (1 + random.Float64()*random.Float64()*0.02)
+		low := math.Min(open, price) * (1 - random.Float64()*random.Float64()*0.02)
+
+		klines = append(klines, kline(open, price, high, low))
+	}
+
+	found := make(map[string]int)
+
+	for _, pattern := range FindPatterns(klines, Names(), 0) {
+
+		found[pattern.Name]++
+
+		if !(pattern.Confidence >= 0 && pattern.Confidence <= 1) {
+			t.Fatalf("%s on candle %d has confidence %f", pattern.Name, pattern.Index, pattern.Confidence)
+		}
+	}
+
+	for _, name := range Names() {
+
+		if found[name] == 0 {
+			t.Errorf("%s isn't found on random candles", name)
+		}
+	}
+}
+
+func TestConfirmsPivot(t *testing.T) {
+
+	klines := []candlescommon.KLine{kline(10, 9, 10.1, 8.9), kline(9, 8.5, 9.1, 8.4), kline(8.4, 9.5, 9.6, 8.3)}
+
+	if !ConfirmsPivot(klines, 1, []string{BullishEngulfing}, 0) {
+		t.Errorf("engulfing on the next candle doesn't confirm pivot")
+	}
+
+	if ConfirmsPivot(klines[:2], 1, []string{BullishEngulfing}, 0) {
+		t.Errorf("pivot is confirmed without the next candle")
+	}
+
+	if ConfirmsPivot(klines, 0, []string{BullishEngulfing}, 0) {
+		t.Errorf("pattern two candles after pivot confirms it")
+	}
+}
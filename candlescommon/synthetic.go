@@ -0,0 +1,148 @@
+package candlescommon
+
+import (
+	"math"
+)
+
+const (
+	SyntheticProduct = "*"
+	SyntheticRatio   = "/"
+)
+
+// CombineKlines builds synthetic klines from two ascending series, candles are matched by open time
+// open and close are exact, high and low are the widest possible bounds limited by open and close.
+// Candle is linked to previous one only if both candles link to it, so gap in any series stays a gap.
+// Candles with not positive price in any series are skipped
+func CombineKlines(symbol string, first []KLine, second []KLine, operation string) []KLine {
+
+	result := make([]KLine, 0)
+
+	i, j := 0, 0
+
+	for i < len(first) && j < len(second) {
+
+		if first[i].OpenTime < second[j].OpenTime {
+			i++
+			continue
+		}
+
+		if first[i].OpenTime > second[j].OpenTime {
+			j++
+			continue
+		}
+
+		a, b := first[i], second[j]
+
+		i++
+		j++
+
+		if !positivePrices(a) || !positivePrices(b) {
+			continue
+		}
+
+		kline := KLine{
+			Symbol:    symbol,
+			OpenTime:  a.OpenTime,
+			CloseTime: a.CloseTime,
+			Closed:    a.Closed && b.Closed,
+		}
+
+		//unclosed candle can have shorter close time
+		if b.CloseTime < kline.CloseTime {
+			kline.CloseTime = b.CloseTime
+		}
+
+		if operation == SyntheticRatio {
+
+			kline.OpenPrice = a.OpenPrice / b.OpenPrice
+			kline.ClosePrice = a.ClosePrice / b.ClosePrice
+			kline.HighPrice = a.HighPrice / b.LowPrice
+			kline.LowPrice = a.LowPrice / b.HighPrice
+
+			kline.QuoteVolume = a.QuoteVolume / b.ClosePrice
+			kline.TakerBuyQuoteVolume = a.TakerBuyQuoteVolume / b.ClosePrice
+
+		} else {
+
+			kline.OpenPrice = a.OpenPrice * b.OpenPrice
+			kline.ClosePrice = a.ClosePrice * b.ClosePrice
+			kline.HighPrice = a.HighPrice * b.HighPrice
+			kline.LowPrice = a.LowPrice * b.LowPrice
+
+			kline.QuoteVolume = a.QuoteVolume * b.ClosePrice
+			kline.TakerBuyQuoteVolume = a.TakerBuyQuoteVolume * b.ClosePrice
+		}
+
+		//volume is counted in base asset of first symbol
+		kline.BaseVolume = a.BaseVolume
+		kline.TakerBuyBaseVolume = a.TakerBuyBaseVolume
+
+		kline.HighPrice = math.Max(kline.HighPrice, math.Max(kline.OpenPrice, kline.ClosePrice))
+		kline.LowPrice = math.Min(kline.LowPrice, math.Min(kline.OpenPrice, kline.ClosePrice))
+
+		if a.PrevCloseCandleTimestamp == b.PrevCloseCandleTimestamp && (len(result) == 0 || result[len(result)-1].CloseTime == a.PrevCloseCandleTimestamp) {
+			kline.PrevCloseCandleTimestamp = a.PrevCloseCandleTimestamp
+		}
+
+		result = append(result, kline)
+	}
+
+	return result
+}
+
+// positivePrices returns true if all prices of kline are above zero
+func positivePrices(kline KLine) bool {
+
+	return kline.OpenPrice > 0 && kline.ClosePrice > 0 && kline.LowPrice > 0 && kline.HighPrice > 0
+}
+
+// AlignKlines returns rows of candles with equal open time that present in every ascending series
+func AlignKlines(series [][]KLine) [][]KLine {
+
+	rows := make([][]KLine, 0)
+
+	if len(series) == 0 {
+		return rows
+	}
+
+	positions := make([]int, len(series))
+
+	for {
+
+		maxOpenTime := uint64(0)
+
+		for idx, klines := range series {
+
+			if positions[idx] >= len(klines) {
+				return rows
+			}
+
+			if klines[positions[idx]].OpenTime > maxOpenTime {
+				maxOpenTime = klines[positions[idx]].OpenTime
+			}
+		}
+
+		aligned := true
+
+		for idx, klines := range series {
+
+			if klines[positions[idx]].OpenTime < maxOpenTime {
+				positions[idx]++
+				aligned = false
+			}
+		}
+
+		if !aligned {
+			continue
+		}
+
+		row := make([]KLine, len(series))
+
+		for idx, klines := range series {
+			row[idx] = klines[positions[idx]]
+			positions[idx]++
+		}
+
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,163 @@
+package candlescommon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCombineKlines(t *testing.T) {
+
+	first := pricedKLines([5]float64{10, 12, 9, 11, 2}, [5]float64{11, 13, 10, 12, 3})
+	first[0].QuoteVolume = 20
+
+	second := pricedKLines([5]float64{2, 4, 1, 2, 5}, [5]float64{2, 2.5, 2, 2.5, 5})
+
+	cases := []struct {
+		name      string
+		operation string
+		prices    [][4]float64
+		quote     float64
+	}{
+		{
+			name:      "ratio",
+			operation: SyntheticRatio,
+			prices:    [][4]float64{{5, 12, 2.25, 5.5}, {5.5, 6.5, 4, 4.8}},
+			quote:     10,
+		},
+		{
+			name:      "product",
+			operation: SyntheticProduct,
+			prices:    [][4]float64{{20, 48, 9, 22}, {22, 32.5, 20, 30}},
+			quote:     40,
+		},
+	}
+
+	for _, c := range cases {
+
+		klines := CombineKlines("SYN", first, second, c.operation)
+
+		if len(klines) != len(c.prices) {
+			t.Fatalf("%s: %d klines, expected %d", c.name, len(klines), len(c.prices))
+		}
+
+		for idx, kline := range klines {
+
+			if found := [4]float64{kline.OpenPrice, kline.HighPrice, kline.LowPrice, kline.ClosePrice}; found != c.prices[idx] {
+				t.Errorf("%s: kline %d prices %v, expected %v", c.name, idx, found, c.prices[idx])
+			}
+
+			if kline.Symbol != "SYN" || kline.BaseVolume != first[idx].BaseVolume || kline.OpenTime != first[idx].OpenTime {
+				t.Errorf("%s: kline %d is %+v", c.name, idx, kline)
+			}
+		}
+
+		if klines[0].QuoteVolume != c.quote {
+			t.Errorf("%s: quote volume %f, expected %f", c.name, klines[0].QuoteVolume, c.quote)
+		}
+
+		if klines[1].PrevCloseCandleTimestamp != klines[0].CloseTime {
+			t.Errorf("%s: klines aren't linked", c.name)
+		}
+	}
+}
+
+func TestCombineKlinesGaps(t *testing.T) {
+
+	cases := []struct {
+		name      string
+		first     []KLine
+		second    []KLine
+		openTimes []uint64
+		linked    []bool
+	}{
+		{
+			name:      "aligned",
+			first:     chainedKLines(0, 1, 2),
+			second:    chainedKLines(0, 1, 2),
+			openTimes: []uint64{0, minute, 2 * minute},
+			linked:    []bool{false, true, true},
+		},
+		{
+			name:      "not matched candles",
+			first:     chainedKLines(0, 1, 2, 3),
+			second:    chainedKLines(1, 2, 3, 4),
+			openTimes: []uint64{minute, 2 * minute, 3 * minute},
+			linked:    []bool{false, true, true},
+		},
+		{
+			name:      "gap in second series",
+			first:     chainedKLines(0, 1, 2, 3),
+			second:    chainedKLines(0, 2, 3),
+			openTimes: []uint64{0, 2 * minute, 3 * minute},
+			linked:    []bool{false, false, true},
+		},
+		{
+			name:      "gap in first series",
+			first:     chainedKLines(0, 2),
+			second:    chainedKLines(0, 1, 2),
+			openTimes: []uint64{0, 2 * minute},
+			linked:    []bool{false, false},
+		},
+	}
+
+	for _, c := range cases {
+
+		klines := CombineKlines("SYN", c.first, c.second, SyntheticRatio)
+
+		openTimes := make([]uint64, 0, len(klines))
+		linked := make([]bool, 0, len(klines))
+
+		for idx, kline := range klines {
+
+			openTimes = append(openTimes, kline.OpenTime)
+			linked = append(linked, idx > 0 && kline.PrevCloseCandleTimestamp == klines[idx-1].CloseTime)
+
+			if !linked[idx] && kline.PrevCloseCandleTimestamp != 0 {
+				t.Errorf("%s: kline %d links to %d", c.name, idx, kline.PrevCloseCandleTimestamp)
+			}
+		}
+
+		if !reflect.DeepEqual(openTimes, c.openTimes) || !reflect.DeepEqual(linked, c.linked) {
+			t.Errorf("%s: open times %v linked %v, expected %v %v", c.name, openTimes, linked, c.openTimes, c.linked)
+		}
+	}
+}
+
+func TestCombineKlinesZeroPrice(t *testing.T) {
+
+	second := chainedKLines(0, 1, 2)
+	second[1].LowPrice = 0
+
+	klines := CombineKlines("SYN", chainedKLines(0, 1, 2), second, SyntheticRatio)
+
+	if len(klines) != 2 || klines[0].OpenTime != 0 || klines[1].OpenTime != 2*minute || klines[1].PrevCloseCandleTimestamp != 0 {
+		t.Errorf("klines with zero price %+v", klines)
+	}
+}
+
+func TestAlignKlines(t *testing.T) {
+
+	rows := AlignKlines([][]KLine{chainedKLines(0, 1, 2, 4), chainedKLines(1, 2, 3, 4), chainedKLines(0, 2, 4, 5)})
+
+	openTimes := make([]uint64, 0, len(rows))
+
+	for _, row := range rows {
+
+		for _, kline := range row {
+
+			if kline.OpenTime != row[0].OpenTime {
+				t.Errorf("row isn't aligned %+v", row)
+			}
+		}
+
+		openTimes = append(openTimes, row[0].OpenTime)
+	}
+
+	if expected := []uint64{2 * minute, 4 * minute}; !reflect.DeepEqual(openTimes, expected) {
+		t.Errorf("aligned open times %v, expected %v", openTimes, expected)
+	}
+
+	if rows := AlignKlines(nil); len(rows) != 0 {
+		t.Errorf("rows of no series %+v", rows)
+	}
+}
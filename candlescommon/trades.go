@@ -0,0 +1,90 @@
+package candlescommon
+
+import (
+	"math"
+)
+
+type Trade struct {
+	ID           uint64
+	Price        float64
+	Quantity     float64
+	Timestamp    uint64
+	IsBuyerMaker bool
+}
+
+// TradesToKlines groups ascending trades to klines of interval with open time in [fromOpenTime, toOpenTime]
+// intervals without trades are filled with flat candles, intervals before first trade are skipped
+func TradesToKlines(symbol string, trades []Trade, interval Interval, fromOpenTime uint64, toOpenTime uint64) []KLine {
+
+	klines := make([]KLine, 0)
+
+	duration := interval.Milliseconds()
+
+	if duration == 0 {
+		return klines
+	}
+
+	fromOpenTime = fromOpenTime / duration * duration
+	tradeIdx := 0
+
+	//skip trades that are out of range
+	for tradeIdx < len(trades) && trades[tradeIdx].Timestamp < fromOpenTime {
+		tradeIdx++
+	}
+
+	for openTime := fromOpenTime; openTime <= toOpenTime; openTime += duration {
+
+		kline := KLine{Symbol: symbol, OpenTime: openTime, CloseTime: openTime + duration - 1, Closed: true}
+
+		hasTrades := false
+
+		for ; tradeIdx < len(trades) && trades[tradeIdx].Timestamp <= kline.CloseTime; tradeIdx++ {
+
+			trade := trades[tradeIdx]
+
+			if !hasTrades {
+
+				kline.OpenPrice = trade.Price
+				kline.HighPrice = trade.Price
+				kline.LowPrice = trade.Price
+				hasTrades = true
+			}
+
+			kline.ClosePrice = trade.Price
+			kline.HighPrice = math.Max(kline.HighPrice, trade.Price)
+			kline.LowPrice = math.Min(kline.LowPrice, trade.Price)
+
+			kline.BaseVolume += trade.Quantity
+			kline.QuoteVolume += trade.Quantity * trade.Price
+
+			//if buyer is not maker, buyer is taker
+			if !trade.IsBuyerMaker {
+				kline.TakerBuyBaseVolume += trade.Quantity
+				kline.TakerBuyQuoteVolume += trade.Quantity * trade.Price
+			}
+		}
+
+		if !hasTrades {
+
+			//wait for first trade
+			if len(klines) == 0 {
+				continue
+			}
+
+			prevClose := klines[len(klines)-1].ClosePrice
+
+			kline.OpenPrice = prevClose
+			kline.ClosePrice = prevClose
+			kline.HighPrice = prevClose
+			kline.LowPrice = prevClose
+		}
+
+		if len(klines) > 0 {
+			kline.PrevCloseCandleTimestamp = klines[len(klines)-1].CloseTime
+		}
+
+		klines = append(klines, kline)
+	}
+
+	return klines
+}
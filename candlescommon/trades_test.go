@@ -0,0 +1,50 @@
+package candlescommon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTradesToKlines(t *testing.T) {
+
+	second := uint64(1000)
+	interval := Interval{Duration: 5, Letter: "s"}
+
+	trades := []Trade{
+		//before range
+		{ID: 1, Price: 1, Quantity: 1, Timestamp: 4 * second},
+		{ID: 2, Price: 10, Quantity: 1, Timestamp: 11 * second, IsBuyerMaker: true},
+		{ID: 3, Price: 12, Quantity: 2, Timestamp: 12 * second},
+		{ID: 4, Price: 9, Quantity: 1, Timestamp: 14*second + 999, IsBuyerMaker: true},
+		//the next candle is empty
+		{ID: 5, Price: 11, Quantity: 3, Timestamp: 20 * second},
+		//after range
+		{ID: 6, Price: 20, Quantity: 1, Timestamp: 30 * second},
+	}
+
+	klines := TradesToKlines("ETHUSDT", trades, interval, 5*second, 25*second)
+
+	expected := []KLine{
+		{Symbol: "ETHUSDT", OpenTime: 10 * second, CloseTime: 15*second - 1, OpenPrice: 10, ClosePrice: 9, HighPrice: 12, LowPrice: 9, BaseVolume: 4, QuoteVolume: 43, TakerBuyBaseVolume: 2, TakerBuyQuoteVolume: 24, Closed: true},
+		{Symbol: "ETHUSDT", OpenTime: 15 * second, CloseTime: 20*second - 1, OpenPrice: 9, ClosePrice: 9, HighPrice: 9, LowPrice: 9, PrevCloseCandleTimestamp: 15*second - 1, Closed: true},
+		{Symbol: "ETHUSDT", OpenTime: 20 * second, CloseTime: 25*second - 1, OpenPrice: 11, ClosePrice: 11, HighPrice: 11, LowPrice: 11, BaseVolume: 3, QuoteVolume: 33, TakerBuyBaseVolume: 3, TakerBuyQuoteVolume: 33, PrevCloseCandleTimestamp: 20*second - 1, Closed: true},
+		{Symbol: "ETHUSDT", OpenTime: 25 * second, CloseTime: 30*second - 1, OpenPrice: 11, ClosePrice: 11, HighPrice: 11, LowPrice: 11, PrevCloseCandleTimestamp: 25*second - 1, Closed: true},
+	}
+
+	if !reflect.DeepEqual(klines, expected) {
+		t.Fatalf("klines %+v\nexpected %+v", klines, expected)
+	}
+
+	//candles before the first trade are skipped, not aligned range starts from candle that contains it
+	if klines := TradesToKlines("ETHUSDT", trades[4:5], interval, 7*second, 20*second); len(klines) != 1 || klines[0].OpenTime != 20*second {
+		t.Errorf("klines before the first trade %+v", klines)
+	}
+
+	if klines := TradesToKlines("ETHUSDT", nil, interval, 0, 25*second); len(klines) != 0 {
+		t.Errorf("klines without trades %+v", klines)
+	}
+
+	if klines := TradesToKlines("ETHUSDT", trades, Interval{Duration: 1, Letter: "M"}, 0, 25*second); len(klines) != 0 {
+		t.Errorf("klines of interval without fixed length %+v", klines)
+	}
+}
@@ -0,0 +1,208 @@
+package candlescommon
+
+import (
+	"strconv"
+	"time"
+)
+
+const (
+	ProblemLowAboveBody       = "low above open/close"
+	ProblemHighBelowBody      = "high below open/close"
+	ProblemNegativeVolume     = "negative volume"
+	ProblemTakerAboveVolume   = "taker volume above total volume"
+	ProblemOpenTimeNotAligned = "open time not aligned to interval"
+	ProblemCloseTimeMismatch  = "close time not matched interval"
+	ProblemBrokenChain        = "previous close timestamp mismatch"
+)
+
+type CandleIssue struct {
+	Index    int
+	OpenTime uint64
+	Problems []string
+}
+
+type CandleGap struct {
+	From         uint64
+	To           uint64
+	MissedCount  uint64
+	AfterCandle  int
+	BeforeCandle int
+}
+
+type CandlesReport struct {
+	Symbol     string
+	Interval   string
+	Count      int
+	FirstOpen  uint64
+	LastOpen   uint64
+	Valid      bool
+	Issues     []CandleIssue
+	Duplicates []uint64
+	OutOfOrder []uint64
+	Gaps       []CandleGap
+}
+
+func (interval Interval) String() string {
+	return strconv.FormatUint(uint64(interval.Duration), 10) + interval.Letter
+}
+
+// Milliseconds returns interval length, months have variable length, so 0 returned for them
+func (interval Interval) Milliseconds() uint64 {
+
+	duration := uint64(interval.Duration)
+
+	switch interval.Letter {
+	case "s":
+		return duration * 1000
+	case "m":
+		return duration * 60 * 1000
+	case "h":
+		return duration * 60 * 60 * 1000
+	case "d":
+		return duration * 24 * 60 * 60 * 1000
+	case "w":
+		return duration * 7 * 24 * 60 * 60 * 1000
+	}
+
+	return 0
+}
+
+// IsAlignedOpenTime checks that candle starts at the beginning of interval
+func (interval Interval) IsAlignedOpenTime(openTime uint64) bool {
+
+	switch interval.Letter {
+
+	case "M":
+		t := time.Unix(int64(openTime/1000), 0).UTC()
+		return openTime%1000 == 0 && t.Day() == 1 && t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0
+
+	case "w":
+		//weeks start from monday, first monday after epoch is 4 days later
+		offset := uint64(4 * 24 * 60 * 60 * 1000)
+		return openTime >= offset && (openTime-offset)%interval.Milliseconds() == 0
+	}
+
+	duration := interval.Milliseconds()
+
+	if duration == 0 {
+		return true
+	}
+
+	return openTime%duration == 0
+}
+
+// ExpectedCloseTime returns close time of candle started at openTime
+func (interval Interval) ExpectedCloseTime(openTime uint64) uint64 {
+
+	if interval.Letter == "M" {
+		t := time.Unix(int64(openTime/1000), 0).UTC()
+		return uint64(t.AddDate(0, int(interval.Duration), 0).Unix())*1000 - 1
+	}
+
+	return openTime + interval.Milliseconds() - 1
+}
+
+func validateCandle(kline KLine) []string {
+
+	problems := make([]string, 0)
+
+	if kline.LowPrice > kline.OpenPrice || kline.LowPrice > kline.ClosePrice {
+		problems = append(problems, ProblemLowAboveBody)
+	}
+
+	if kline.HighPrice < kline.OpenPrice || kline.HighPrice < kline.ClosePrice {
+		problems = append(problems, ProblemHighBelowBody)
+	}
+
+	if kline.BaseVolume < 0 || kline.QuoteVolume < 0 || kline.TakerBuyBaseVolume < 0 || kline.TakerBuyQuoteVolume < 0 {
+		problems = append(problems, ProblemNegativeVolume)
+	}
+
+	if kline.TakerBuyBaseVolume > kline.BaseVolume || kline.TakerBuyQuoteVolume > kline.QuoteVolume {
+		problems = append(problems, ProblemTakerAboveVolume)
+	}
+
+	return problems
+}
+
+// ValidateCandles checks candles in ascending order and returns report about all found problems
+func ValidateCandles(klines []KLine, interval Interval) CandlesReport {
+
+	report := CandlesReport{
+		Interval:   interval.String(),
+		Count:      len(klines),
+		Issues:     make([]CandleIssue, 0),
+		Duplicates: make([]uint64, 0),
+		OutOfOrder: make([]uint64, 0),
+		Gaps:       make([]CandleGap, 0),
+	}
+
+	if len(klines) == 0 {
+		report.Valid = true
+		return report
+	}
+
+	report.Symbol = klines[0].Symbol
+	report.FirstOpen = klines[0].OpenTime
+	report.LastOpen = klines[len(klines)-1].OpenTime
+
+	duration := interval.Milliseconds()
+
+	//repeated open time can be far from the first one when candles are out of order
+	openTimes := make(map[uint64]bool, len(klines))
+
+	for idx, kline := range klines {
+
+		problems := validateCandle(kline)
+
+		if !interval.IsAlignedOpenTime(kline.OpenTime) {
+			problems = append(problems, ProblemOpenTimeNotAligned)
+		}
+
+		//unclosed candle can have any close time
+		if kline.Closed && kline.CloseTime != interval.ExpectedCloseTime(kline.OpenTime) {
+			problems = append(problems, ProblemCloseTimeMismatch)
+		}
+
+		if openTimes[kline.OpenTime] {
+
+			report.Duplicates = append(report.Duplicates, kline.OpenTime)
+
+		} else if idx > 0 {
+
+			prev := klines[idx-1]
+
+			if kline.OpenTime < prev.OpenTime {
+
+				report.OutOfOrder = append(report.OutOfOrder, kline.OpenTime)
+
+			} else {
+
+				if kline.OpenTime > prev.CloseTime+1 {
+
+					gap := CandleGap{From: prev.CloseTime + 1, To: kline.OpenTime - 1, AfterCandle: idx - 1, BeforeCandle: idx}
+
+					if duration > 0 {
+						gap.MissedCount = (kline.OpenTime - prev.CloseTime - 1) / duration
+					}
+
+					report.Gaps = append(report.Gaps, gap)
+				}
+
+				if kline.PrevCloseCandleTimestamp != prev.CloseTime {
+					problems = append(problems, ProblemBrokenChain)
+				}
+			}
+		}
+
+		openTimes[kline.OpenTime] = true
+
+		if len(problems) > 0 {
+			report.Issues = append(report.Issues, CandleIssue{Index: idx, OpenTime: kline.OpenTime, Problems: problems})
+		}
+	}
+
+	report.Valid = len(report.Issues) == 0 && len(report.Duplicates) == 0 && len(report.OutOfOrder) == 0 && len(report.Gaps) == 0
+
+	return report
+}
@@ -0,0 +1,119 @@
+package candlescommon
+
+import (
+	"reflect"
+	"testing"
+)
+
+const minute = uint64(60000)
+
+// chainedKLines returns closed 1m candles opened at minutes, every candle links to previous one
+func chainedKLines(minutes ...uint64) []KLine {
+
+	klines := make([]KLine, 0, len(minutes))
+
+	for idx, m := range minutes {
+
+		kline := KLine{OpenTime: m * minute, CloseTime: (m+1)*minute - 1, OpenPrice: 10, ClosePrice: 11, HighPrice: 12, LowPrice: 9, Closed: true}
+
+		if idx > 0 {
+			kline.PrevCloseCandleTimestamp = klines[idx-1].CloseTime
+		}
+
+		klines = append(klines, kline)
+	}
+
+	return klines
+}
+
+func TestValidateCandles(t *testing.T) {
+
+	wrongPrices := chainedKLines(0, 1, 2)
+	wrongPrices[1].LowPrice = 10.5
+	wrongPrices[1].HighPrice = 10.5
+	wrongPrices[2].BaseVolume = 1
+	wrongPrices[2].TakerBuyBaseVolume = 2
+
+	notAligned := chainedKLines(0, 1)
+	notAligned[1].OpenTime += 1000
+	notAligned[1].CloseTime += 1000
+
+	brokenChain := chainedKLines(0, 1)
+	brokenChain[1].PrevCloseCandleTimestamp = 0
+
+	cases := []struct {
+		name       string
+		klines     []KLine
+		issues     []CandleIssue
+		duplicates []uint64
+		outOfOrder []uint64
+		gaps       []CandleGap
+	}{
+		{
+			name:   "valid",
+			klines: chainedKLines(0, 1, 2, 3),
+		},
+		{
+			name:   "gap",
+			klines: chainedKLines(0, 1, 4),
+			gaps:   []CandleGap{{From: 2 * minute, To: 4*minute - 1, MissedCount: 2, AfterCandle: 1, BeforeCandle: 2}},
+		},
+		{
+			name:       "adjacent duplicate",
+			klines:     chainedKLines(0, 1, 1, 2),
+			duplicates: []uint64{minute},
+		},
+		{
+			name:       "not adjacent duplicate",
+			klines:     chainedKLines(0, 1, 2, 1),
+			duplicates: []uint64{minute},
+		},
+		{
+			name:       "out of order",
+			klines:     chainedKLines(0, 2, 1),
+			outOfOrder: []uint64{minute},
+			gaps:       []CandleGap{{From: minute, To: 2*minute - 1, MissedCount: 1, AfterCandle: 0, BeforeCandle: 1}},
+		},
+		{
+			name:   "prices and volumes",
+			klines: wrongPrices,
+			issues: []CandleIssue{
+				{Index: 1, OpenTime: minute, Problems: []string{ProblemLowAboveBody, ProblemHighBelowBody}},
+				{Index: 2, OpenTime: 2 * minute, Problems: []string{ProblemTakerAboveVolume}},
+			},
+		},
+		{
+			name:   "not aligned",
+			klines: notAligned,
+			issues: []CandleIssue{{Index: 1, OpenTime: minute + 1000, Problems: []string{ProblemOpenTimeNotAligned}}},
+			gaps:   []CandleGap{{From: minute, To: minute + 999, MissedCount: 0, AfterCandle: 0, BeforeCandle: 1}},
+		},
+		{
+			name:   "broken chain",
+			klines: brokenChain,
+			issues: []CandleIssue{{Index: 1, OpenTime: minute, Problems: []string{ProblemBrokenChain}}},
+		},
+	}
+
+	for _, c := range cases {
+
+		report := ValidateCandles(c.klines, Interval{Duration: 1, Letter: "m"})
+
+		expected := CandlesReport{
+			Interval:   "1m",
+			Count:      len(c.klines),
+			FirstOpen:  c.klines[0].OpenTime,
+			LastOpen:   c.klines[len(c.klines)-1].OpenTime,
+			Issues:     append([]CandleIssue{}, c.issues...),
+			Duplicates: append([]uint64{}, c.duplicates...),
+			OutOfOrder: append([]uint64{}, c.outOfOrder...),
+			Gaps:       append([]CandleGap{}, c.gaps...),
+		}
+
+		expected.Valid = len(c.issues) == 0 && len(c.duplicates) == 0 && len(c.outOfOrder) == 0 && len(c.gaps) == 0
+
+		if !reflect.DeepEqual(report, expected) {
+			t.Errorf("%s: report %+v, expected %+v", c.name, report, expected)
+		}
+	}
+}
@@ -44,10 +44,98 @@ func InitializeDatabase() {
 		}
 	}
 
+	DatabaseManager.Exec(`CREATE TABLE IF NOT EXISTS public.tran_indexes
+(
+    name character varying COLLATE pg_catalog."default" NOT NULL,
+    definition text NOT NULL,
+    CONSTRAINT primary_indexes PRIMARY KEY (name)
+)`)
+
+	//combinations kept by live sequences, options is JSON of sequence options and empty is lows of the default rule
+	DatabaseManager.Exec(`CREATE TABLE IF NOT EXISTS public.tran_live_sequences
+(
+    symbol character varying COLLATE pg_catalog."default" NOT NULL,
+    "interval" character varying COLLATE pg_catalog."default" NOT NULL,
+    "centralRSI" integer NOT NULL,
+    options text NOT NULL DEFAULT '',
+    CONSTRAINT primary_live_sequences PRIMARY KEY (symbol, "interval", "centralRSI", options)
+)`)
+
+	DatabaseManager.Exec(`CREATE TABLE IF NOT EXISTS public."tran_bestPeriodsList"
+(
+    symbol character varying COLLATE pg_catalog."default" NOT NULL,
+    "interval" character varying COLLATE pg_catalog."default" NOT NULL,
+    "centralRSI" integer NOT NULL DEFAULT 15,
+    list text NOT NULL,
+    "lastUpdate" bigint NOT NULL,
+    "lastRSI" text NOT NULL
+)`)
+
+	//sequences saved before central RSI was stored were counted with central RSI 15
+	DatabaseManager.Exec(`ALTER TABLE public."tran_bestPeriodsList" ADD COLUMN IF NOT EXISTS "centralRSI" integer NOT NULL DEFAULT 15`)
+
+	DatabaseManager.Exec(`CREATE INDEX IF NOT EXISTS "tran_bestPeriodsList_level" ON public."tran_bestPeriodsList" (symbol, "interval", "centralRSI", "lastUpdate")`)
+
+	//sequence snapshot is header with RSI settings, entries of sequence stack and RSI averages per period
+	DatabaseManager.Exec(`CREATE TABLE IF NOT EXISTS public.tran_sequence_snapshots
+(
+    id bigserial NOT NULL,
+    symbol character varying COLLATE pg_catalog."default" NOT NULL,
+    "interval" character varying COLLATE pg_catalog."default" NOT NULL,
+    "centralRSI" integer NOT NULL,
+    options character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+    "lastUpdate" bigint NOT NULL,
+    smoothing character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+    source character varying COLLATE pg_catalog."default" NOT NULL DEFAULT '',
+    "pointsCount" bigint NOT NULL,
+    "lastValue" double precision NOT NULL,
+    gains double precision[],
+    losses double precision[],
+    CONSTRAINT primary_sequence_snapshots PRIMARY KEY (id),
+    CONSTRAINT unique_sequence_snapshots_options UNIQUE (symbol, "interval", "centralRSI", options, "lastUpdate")
+)`)
+
+	//snapshots saved before options are lows of the default rule
+	DatabaseManager.Exec(`ALTER TABLE public.tran_sequence_snapshots ADD COLUMN IF NOT EXISTS options character varying COLLATE pg_catalog."default" NOT NULL DEFAULT ''`)
+	DatabaseManager.Exec(`ALTER TABLE public.tran_sequence_snapshots DROP CONSTRAINT IF EXISTS unique_sequence_snapshots`)
+	DatabaseManager.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS unique_sequence_snapshots_options ON public.tran_sequence_snapshots (symbol, "interval", "centralRSI", options, "lastUpdate")`)
+
+	DatabaseManager.Exec(`CREATE TABLE IF NOT EXISTS public.tran_sequence_entries
+(
+    "snapshotId" bigint NOT NULL REFERENCES public.tran_sequence_snapshots (id) ON DELETE CASCADE,
+    "position" integer NOT NULL,
+    sequence integer NOT NULL,
+    "lowCentralPrice" boolean NOT NULL,
+    "centralPrice" double precision NOT NULL,
+    fictive boolean NOT NULL,
+    "timestamp" bigint NOT NULL,
+    central double precision NOT NULL,
+    lower double precision NOT NULL,
+    up double precision NOT NULL,
+    down double precision NOT NULL,
+    count bigint NOT NULL,
+    "insufficientHistory" boolean NOT NULL DEFAULT false,
+    CONSTRAINT primary_sequence_entries PRIMARY KEY ("snapshotId", "position")
+)`)
+
+	DatabaseManager.Exec(`ALTER TABLE public.tran_sequence_entries ADD COLUMN IF NOT EXISTS "ladderPosition" character varying COLLATE pg_catalog."default" NOT NULL DEFAULT ''`)
+
+	DatabaseManager.Exec(`CREATE INDEX IF NOT EXISTS sequence_entries_period ON public.tran_sequence_entries (sequence, count, "timestamp")`)
+
+	DatabaseManager.Exec(`CREATE TABLE IF NOT EXISTS public.tran_sequence_rsi
+(
+    "snapshotId" bigint NOT NULL REFERENCES public.tran_sequence_snapshots (id) ON DELETE CASCADE,
+    period integer NOT NULL,
+    "avgGain" double precision NOT NULL,
+    "avgLoss" double precision NOT NULL,
+    CONSTRAINT primary_sequence_rsi PRIMARY KEY ("snapshotId", period)
+)`)
+
 }
 func GetDatabaseSupportedTimeframes() map[string][]uint {
 
 	return map[string][]uint{
+		"s": {5, 15, 30},
 		"m": {1, 2, 3, 4, 5, 21, 72},
 		"h": {1, 4, 6},
 		"d": {1, 3},
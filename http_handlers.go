@@ -3,22 +3,74 @@ package main
 import (
 	"container/list"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"math"
 	"net/http"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/NERON/tran/candlescommon"
+	"github.com/NERON/tran/candlescommon/patterns"
 	"github.com/NERON/tran/indicators"
 	"github.com/NERON/tran/manager"
 	"github.com/gorilla/mux"
 	"gonum.org/v1/gonum/stat/combin"
 )
 
+var errDataNotExist = errors.New("Data not exist")
+var errWrongCandles = errors.New("candles are not consistent")
+var errSequencesNotUpdated = errors.New("sequences are not updated till the last candles")
+
+// intervals of groups with mode 0 and other modes
+var hourGroupIntervals = []string{
+	"1h",
+	"72m",
+	"80m",
+	"90m",
+	"96m",
+	"2h",
+	"144m",
+	"160m",
+	"3h",
+	"4h",
+	"288m",
+	"6h",
+	"8h",
+	"12h",
+}
+
+var minuteGroupIntervals = []string{
+	"1m",
+	"2m",
+	"3m",
+	"4m",
+	"5m",
+	"6m",
+	"8m",
+	"9m",
+	"10m",
+	"12m",
+	"14m",
+	"15m",
+	"16m",
+	"18m",
+	"20m",
+	"21m",
+	"24m",
+	"25m",
+	"30m",
+	"32m",
+	"36m",
+	"40m",
+	"42m",
+	"45m",
+	"48m",
+}
+
 func IndexHandler(w http.ResponseWriter, r *http.Request) {
 
 	type Data struct {
@@ -70,92 +122,52 @@ func TestHandler(w http.ResponseWriter, r *http.Request) {
 
 	for _, symbol := range symbols {
 
-		rsiP := indicators.NewRSIMultiplePeriods(250)
+		rsiP := indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod)
 
 		candles, _ := manager.GetLastKLines(symbol, interval, 100000)
 
-		candlesOld, _ := manager.GetLastKLinesFromTimestamp(symbol, interval, candles[0].OpenTime, 2000)
-
-		for _, candleOld := range candlesOld {
-			rsiP.AddPoint(candleOld.ClosePrice)
-
-		}
-
-		lowReverse := indicators.NewRSILowReverseIndicator()
-		lowsMap := make(map[int]struct{})
-
-		for idx, candle := range candles {
-
-			lowReverse.AddPoint(candle.LowPrice, 0)
+		candlesOld, _ := manager.GetRSIWarmUpKLines(symbol, interval, candles[0].OpenTime, rsiP)
 
-			if lowReverse.IsPreviousLow() {
-				lowsMap[idx-1] = struct{}{}
-			} else if idx > 0 && candle.OpenPrice < candle.ClosePrice && candles[idx-1].LowPrice >= candle.LowPrice {
-				lowsMap[idx] = struct{}{}
-			}
+		tracker, err := manager.NewSequenceTracker(rsiP, "", float64(centralRSI))
 
+		if err != nil {
+			w.Write([]byte(err.Error()))
+			return
 		}
 
-		sequence := make([]int, 0)
-		klinesSeq := make([]candlescommon.KLine, 0)
+		tracker.Prime(candlesOld)
 
-		for idx, candle := range candles {
-
-			_, ok := lowsMap[idx]
+		for _, candle := range candles {
 
-			if ok {
+			for _, push := range tracker.Add(candle) {
 
-				bestPeriod, rsiVal, _ := rsiP.GetBestPeriod(candle.LowPrice, float64(centralRSI))
-				sequence = append(sequence, bestPeriod)
-				klinesSeq = append(klinesSeq, candle)
+				period := push.Sequence.Sequence
 
-				_, ok := RSIValMap[bestPeriod]
+				_, ok := RSIValMap[period]
 
 				if !ok {
-					RSIValMap[bestPeriod] = make(map[string]int, 0)
+					RSIValMap[period] = make(map[string]int, 0)
 				}
 
-				RSIValMap[bestPeriod][fmt.Sprintf("%.1f", rsiVal)]++
-
-			}
-
-			rsiP.AddPoint(candle.ClosePrice)
-
-		}
-
-		seqStack := list.New()
+				RSIValMap[period][fmt.Sprintf("%.1f", float64(centralRSI))]++
 
-		for i := 0; i < len(sequence); i++ {
+				for _, removed := range push.Removed {
 
-			if sequence[i] == 0 {
-				continue
-			}
-
-			saveVal := 0
-
-			for e := seqStack.Front(); e != nil && e.Value.(int) <= sequence[i]; e = seqStack.Front() {
-
-				saveVal = e.Value.(int)
+					_, ok := transitionMap[removed.Sequence]
 
-				_, ok := transitionMap[saveVal]
+					if !ok {
 
-				if !ok {
+						transitionMap[removed.Sequence] = make(map[int]int)
+					}
 
-					transitionMap[saveVal] = make(map[int]int)
+					transitionMap[removed.Sequence][period]++
 				}
 
-				transitionMap[saveVal][sequence[i]]++
-
-				seqStack.Remove(e)
+				counter, _ := counterMap[period]
+				counter.Counter++
 
+				counterMap[period] = counter
 			}
-
-			counter, _ := counterMap[sequence[i]]
-			counter.Counter++
-
-			counterMap[sequence[i]] = counter
-
-			seqStack.PushFront(sequence[i])
 		}
 
 	}
@@ -182,75 +194,6 @@ func TestHandler(w http.ResponseWriter, r *http.Request) {
 
 }
 
-type AggTradeData struct {
-	AggID     uint64  `json:"a"`
-	Price     float64 `json:"p,string"`
-	Timestamp uint64  `json:"T"`
-}
-
-func GroupAgg(data []AggTradeData) {
-
-	prevVal := uint64(0)
-
-	kline := candlescommon.KLine{}
-
-	period := uint64(15)
-
-	for _, agg := range data {
-
-		if prevVal < agg.Timestamp/1000/period {
-
-			if prevVal > 0 {
-				log.Println(time.Unix(int64(kline.OpenTime/1000), 0), kline.OpenPrice, kline.ClosePrice, kline.LowPrice, kline.HighPrice)
-			}
-
-			kline = candlescommon.KLine{
-				OpenTime:   agg.Timestamp / 1000 / period * period * 1000,
-				CloseTime:  (agg.Timestamp/1000/period+1)*period*1000 - 1,
-				OpenPrice:  agg.Price,
-				ClosePrice: agg.Price,
-				LowPrice:   agg.Price,
-				HighPrice:  agg.Price,
-			}
-		}
-
-		kline.ClosePrice = agg.Price
-		kline.LowPrice = math.Min(kline.LowPrice, agg.Price)
-		kline.HighPrice = math.Max(kline.HighPrice, agg.Price)
-
-		prevVal = agg.Timestamp / 1000 / period
-
-	}
-}
-func SecondHandler() {
-
-	urlS := fmt.Sprintf("https://api.binance.com/api/v3/aggTrades?symbol=%s&startTime=%d&endTime=%d", "ETHUSDT", 1600894200000, 1600894200000+3600*1000)
-
-	resp, err := http.Get(urlS)
-
-	log.Println(urlS)
-
-	if err != nil {
-
-		log.Println(err.Error())
-		return
-	}
-
-	body, err := ioutil.ReadAll(resp.Body)
-	resp.Body.Close()
-
-	var trades []AggTradeData
-
-	err = json.Unmarshal(body, &trades)
-
-	if err != nil {
-		log.Println(err.Error())
-		return
-	}
-
-	GroupAgg(trades)
-
-}
 func NewTesterHandler(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
@@ -301,6 +244,13 @@ func ChartUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		PrevCandleClose uint64
 		Up              float64
 		Down            float64
+		ZoneATR         float64
+		LadderPosition  string             `json:",omitempty"`
+		Indicators      map[string]float64 `json:",omitempty"`
+		Patterns        []patterns.Pattern `json:",omitempty"`
+
+		//RSI of the longest period hasn't converged yet
+		InsufficientHistory bool `json:",omitempty"`
 	}
 
 	vars := mux.Vars(r)
@@ -309,8 +259,16 @@ func ChartUpdateHandler(w http.ResponseWriter, r *http.Request) {
 
 	centralRSI, _ := strconv.ParseUint(vars["centralRSI"], 10, 64)
 
+	//side=high searches resistance zones from highs with overbought central RSI
+	highSide := r.URL.Query().Get("side") == "high"
+
 	if centralRSI == 0 {
+
 		centralRSI = 20
+
+		if highSide {
+			centralRSI = 85
+		}
 	}
 
 	endTimestamp := uint64(0)
@@ -345,30 +303,148 @@ func ChartUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rsiP := indicators.NewRSIMultiplePeriods(250)
+	//RSI smoothing (wilder, ema, sma) and price source (close, low, hl2, hlc3, ohlc4)
+	rsiSmoothing := r.URL.Query().Get("rsiSmoothing")
+	rsiSource := r.URL.Query().Get("rsiSource")
+
+	err = indicators.CheckRSISettings(rsiSmoothing, rsiSource)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	rsiPeriods, err := rsiPeriodsFromRequest(r)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	rsiP := indicators.NewRSIMultiplePeriodsWithPeriods(rsiPeriods, rsiSmoothing, rsiSource)
 
-	candlesOld, err := manager.GetLastKLinesFromTimestamp(vars["symbol"], interval, candles[0].OpenTime, 100)
+	candlesOld, err := manager.GetRSIWarmUpKLines(vars["symbol"], interval, candles[0].OpenTime, rsiP)
 
 	log.Println("candles length", len(candlesOld))
 	if err != nil {
 		log.Println(err.Error())
 	}
 
+	barsType := r.URL.Query().Get("bars")
+
+	if barsType != "" && barsType != candlescommon.BarsTime {
+
+		barsSize, _ := strconv.ParseFloat(r.URL.Query().Get("barsSize"), 64)
+
+		firstOpenTime := candles[0].OpenTime
+
+		//build bars over old and new candles together, so bars are continuous
+		bars, err := candlescommon.BuildBars(append(candlesOld, candles...), barsType, barsSize)
+
+		if err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		idx := sort.Search(len(bars), func(i int) bool {
+			return bars[i].OpenTime >= firstOpenTime
+		})
+
+		candlesOld, candles = bars[:idx], bars[idx:]
+
+		if len(candles) == 0 {
+			log.Println("bars null")
+			return
+		}
+	}
+
+	//additional indicators requested like rsi:14,rsi:7
+	chartIndicators := make(map[string]indicators.Indicator)
+
+	if len(r.URL.Query().Get("indicators")) > 0 {
+
+		for _, description := range strings.Split(r.URL.Query().Get("indicators"), ",") {
+
+			indicator, err := indicators.NewIndicatorFromString(description)
+
+			if err != nil {
+				w.Write([]byte(err.Error()))
+				return
+			}
+
+			chartIndicators[description] = indicator
+		}
+	}
+
+	atrPeriod, _ := strconv.ParseUint(r.URL.Query().Get("atrPeriod"), 10, 64)
+
+	if atrPeriod == 0 {
+		atrPeriod = 14
+	}
+
+	//zones are also expressed in ATR units
+	atr := indicators.NewATR(uint(atrPeriod))
+
+	//trend filter like ema:200 takes only lows above its value
+	var trendFilter indicators.Indicator
+
+	if len(r.URL.Query().Get("trendFilter")) > 0 {
+
+		trendFilter, err = indicators.NewIndicatorFromString(r.URL.Query().Get("trendFilter"))
+
+		if err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+
 	for _, candleOld := range candlesOld {
 
-		rsiP.AddPoint(candleOld.ClosePrice)
+		rsiP.AddCandle(candleOld)
+
+		atr.AddCandle(candleOld)
+
+		for _, indicator := range chartIndicators {
+			indicators.AddCandleToIndicator(indicator, candleOld)
+		}
+
+		if trendFilter != nil {
+			indicators.AddCandleToIndicator(trendFilter, candleOld)
+		}
 
 	}
 
 	updateCandles := make([]ChartUpdateCandle, 0)
 
-	lowReverse := indicators.NewRSILowReverseIndicator()
+	var lowsMap map[int]struct{}
+	var tracker *manager.SequenceTracker
 
-	if len(candlesOld) > 0 {
-		lowReverse.AddPoint(candlesOld[len(candlesOld)-1].LowPrice, 0)
-	}
+	if highSide {
 
-	lowsMap := indicators.GenerateMapLows(lowReverse, candles)
+		highReverse := indicators.NewRSIHighReverseIndicator()
+
+		if len(candlesOld) > 0 {
+			highReverse.AddPoint(candlesOld[len(candlesOld)-1].HighPrice)
+		}
+
+		lowsMap = indicators.GenerateMapHighs(highReverse, candles)
+
+		tracker = manager.NewHighSequenceTracker(rsiP, float64(centralRSI))
+
+	} else {
+
+		//pivots like fractal:2:2, zigzag:5 or zigzagatr:14:3 select low detector
+		tracker, err = manager.NewSequenceTracker(rsiP, r.URL.Query().Get("pivots"), float64(centralRSI))
+
+		if err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		indicators.PrimeLowDetector(tracker.Detector, candlesOld)
+
+		lowsMap = indicators.GenerateMapLows(tracker.Detector, candles)
+	}
 
 	//TODO: Get low reverse for last element
 	if endTimestamp > 0 && candles[len(candles)-1].OpenTime == endTimestamp {
@@ -376,39 +452,98 @@ func ChartUpdateHandler(w http.ResponseWriter, r *http.Request) {
 		candles = candles[:len(candles)-1]
 	}
 
-	bestSequenceList := list.New()
+	//patterns=hammer,doji annotates candles, lowPatterns accepts only lows confirmed by pattern
+	//on the low candle or the next one like sequences, patternConfidence is minimal confidence for both.
+	//Chart shows such low on its candle, sequences get it only after the next candle is closed
+	patternConfidence, _ := strconv.ParseFloat(r.URL.Query().Get("patternConfidence"), 64)
+
+	var annotatePatterns []string
+	var lowPatterns []string
+
+	if _, ok := r.URL.Query()["patterns"]; ok {
+		annotatePatterns = patterns.ParseNames(r.URL.Query().Get("patterns"))
+	}
+
+	if len(r.URL.Query().Get("lowPatterns")) > 0 {
+		lowPatterns = patterns.ParseNames(r.URL.Query().Get("lowPatterns"))
+	}
 
 	for idx, candle := range candles {
 
 		_, ok := lowsMap[idx]
 
+		if ok && lowPatterns != nil {
+			ok = patterns.ConfirmsPivot(candles, idx, lowPatterns, patternConfidence)
+		}
+
+		//highs are accepted only below trend filter
+		if ok && trendFilter != nil {
+
+			trendValue, ready := trendFilter.Value()
+			ok = ready && ((!highSide && candle.LowPrice > trendValue) || (highSide && candle.HighPrice < trendValue))
+		}
+
 		bestPeriod := 0
 		up := float64(0)
 		down := float64(0)
+		zoneATR := float64(0)
+		ladderPosition := ""
 
 		if ok {
 
-			bestPeriod, _, _ = rsiP.GetBestPeriod(candle.LowPrice, float64(centralRSI))
-			up, down, _ = rsiP.GetIntervalForPeriod(bestPeriod, float64(centralRSI))
+			sequence, position, accepted := tracker.Evaluate(candle)
 
-			if bestPeriod > 2 || (bestPeriod == 2 && candle.LowPrice <= up) {
+			//pivot outside of periods zones is reported instead of period
+			if position != indicators.LadderInside {
+				ladderPosition = position.String()
+			}
 
-				for e := bestSequenceList.Front(); e != nil && e.Value.(int) <= bestPeriod; e = bestSequenceList.Front() {
-					bestSequenceList.Remove(e)
-				}
+			if accepted {
 
-				bestSequenceList.PushFront(bestPeriod)
+				tracker.Push(sequence)
 
-			} else {
+				bestPeriod = sequence.Sequence
+				up = sequence.Up
+				down = sequence.Down
 
-				bestPeriod = 0
-				up = 0
-				down = 0
+				zoneATR, _ = atr.ToUnits(up - down)
 			}
 
 		}
 
-		rsiP.AddPoint(candle.ClosePrice)
+		rsiP.AddCandle(candle)
+
+		atr.AddCandle(candle)
+
+		if trendFilter != nil {
+			indicators.AddCandleToIndicator(trendFilter, candle)
+		}
+
+		var indicatorValues map[string]float64
+
+		for description, indicator := range chartIndicators {
+
+			indicators.AddCandleToIndicator(indicator, candle)
+
+			if value, ok := indicator.Value(); ok {
+
+				if indicatorValues == nil {
+					indicatorValues = make(map[string]float64)
+				}
+
+				indicatorValues[description] = value
+
+				if multipleIndicator, isMultiple := indicator.(indicators.MultipleValuesIndicator); isMultiple {
+
+					if values, ready := multipleIndicator.Values(); ready {
+
+						for name, value := range values {
+							indicatorValues[description+"."+name] = value
+						}
+					}
+				}
+			}
+		}
 
 		updateCandles = append(updateCandles, ChartUpdateCandle{
 			OpenTime:        candle.OpenTime,
@@ -423,6 +558,12 @@ func ChartUpdateHandler(w http.ResponseWriter, r *http.Request) {
 			PrevCandleClose: candle.PrevCloseCandleTimestamp,
 			Up:              up,
 			Down:            down,
+			ZoneATR:         zoneATR,
+			LadderPosition:  ladderPosition,
+			Indicators:      indicatorValues,
+			Patterns:        patterns.Recognize(candles, idx, annotatePatterns, patternConfidence),
+
+			InsufficientHistory: !rsiP.IsWarmedUp(indicators.DefaultWarmUpTolerance),
 		})
 	}
 
@@ -440,8 +581,16 @@ func GetIntervalHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	centralRSI, _ := strconv.ParseUint(vars["centralRSI"], 10, 64)
 
+	//side=high returns resistance zones with overbought central RSI
+	highSide := r.URL.Query().Get("side") == "high"
+
 	if centralRSI == 0 {
+
 		centralRSI = 20
+
+		if highSide {
+			centralRSI = 85
+		}
 	}
 
 	intervals := []string{
@@ -480,6 +629,9 @@ func GetIntervalHandler(w http.ResponseWriter, r *http.Request) {
 		Up       float64
 		Down     float64
 		Percent  float64
+		ATRUnits float64
+
+		InsufficientHistory bool `json:",omitempty"`
 	}
 
 	results := make([]Result, 0)
@@ -500,17 +652,10 @@ func GetIntervalHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		candlesGet, err := manager.GetLastKLinesFromTimestamp(vars["symbol"], interval, candles[0].OpenTime, 5000)
-
-		if err != nil {
-
-			w.Write([]byte(err.Error()))
-			return
-		}
-
-		candles = append(candlesGet, candles...)
+		rsiP := indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod)
+		atr := indicators.NewATR(14)
 
-		candlesOld, err := manager.GetLastKLinesFromTimestamp(vars["symbol"], interval, candles[0].OpenTime, 5000)
+		candlesOld, err := manager.GetRSIWarmUpKLines(vars["symbol"], interval, candles[0].OpenTime, rsiP)
 
 		if err != nil {
 
@@ -518,11 +663,10 @@ func GetIntervalHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		rsiP := indicators.NewRSIMultiplePeriods(250)
-
 		for _, candleOld := range candlesOld {
 
 			rsiP.AddPoint(candleOld.ClosePrice)
+			atr.AddCandle(candleOld)
 
 		}
 
@@ -530,13 +674,20 @@ func GetIntervalHandler(w http.ResponseWriter, r *http.Request) {
 
 			if candle.Closed {
 				rsiP.AddPoint(candle.ClosePrice)
+				atr.AddCandle(candle)
 			}
 
 		}
 
 		up, down, _ := rsiP.GetIntervalForPeriod(2, float64(centralRSI))
 
-		results = append(results, Result{Interval: intervalStr, Up: up, Down: down, Percent: (down/up - 1) * 100})
+		if highSide {
+			up, down, _ = rsiP.GetIntervalForPeriodHigh(2, float64(centralRSI))
+		}
+
+		atrUnits, _ := atr.ToUnits(up - down)
+
+		results = append(results, Result{Interval: intervalStr, Up: up, Down: down, Percent: (down/up - 1) * 100, ATRUnits: atrUnits, InsufficientHistory: !rsiP.IsWarmedUp(indicators.DefaultWarmUpTolerance)})
 
 	}
 
@@ -555,8 +706,15 @@ func NewGroupsHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	centralRSI, _ := strconv.ParseUint(vars["centralRSI"], 10, 64)
 
+	options, err := sequenceOptionsFromRequest(r)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
 	if centralRSI == 0 {
-		centralRSI = 15
+		centralRSI = uint64(options.DefaultCentralRSI())
 	}
 
 	intervalRange, _ := strconv.ParseUint(vars["mode"], 10, 64)
@@ -568,57 +726,14 @@ func NewGroupsHandler(w http.ResponseWriter, r *http.Request) {
 		timestamp = math.MaxInt64
 	}
 
-	var intervals []string
-
-	if intervalRange == 0 {
-
-		intervals = []string{
-			"1h",
-			"72m",
-			"80m",
-			"90m",
-			"96m",
-			"2h",
-			"144m",
-			"160m",
-			"3h",
-			"4h",
-			"288m",
-			"6h",
-			"8h",
-			"12h",
-		}
-
-	} else {
+	//bars=renko&barsSize=10 counts sequences on bars built from candles of every interval
+	barsType := r.URL.Query().Get("bars")
+	barsSize, _ := strconv.ParseFloat(r.URL.Query().Get("barsSize"), 64)
 
-		intervals = []string{
-			"1m",
-			"2m",
-			"3m",
-			"4m",
-			"5m",
-			"6m",
-			"8m",
-			"9m",
-			"10m",
-			"12m",
-			"14m",
-			"15m",
-			"16m",
-			"18m",
-			"20m",
-			"21m",
-			"24m",
-			"25m",
-			"30m",
-			"32m",
-			"36m",
-			"40m",
-			"42m",
-			"45m",
-			"48m",
-		}
+	intervals := hourGroupIntervals
 
+	if intervalRange != 0 {
+		intervals = minuteGroupIntervals
 	}
 
 	type SequenceResult struct {
@@ -641,154 +756,58 @@ func NewGroupsHandler(w http.ResponseWriter, r *http.Request) {
 	//iterate over intervals
 	for _, intervalStr := range intervals {
 
-		interval := candlescommon.IntervalFromStr(intervalStr)
-
-		var err error
-		var candles []candlescommon.KLine
-
-		if timestamp == math.MaxInt64 {
-
-			var ok bool
+		bestSequenceList, rsiP, err := groupSequences(vars["symbol"], intervalStr, timestamp, uint(centralRSI), options, barsType, barsSize)
 
-			candles, ok = manager.KLineCacher.GetLatestKLines(vars["symbol"], interval)
-
-			if !ok {
-
-				candles, err = manager.GetLastKLines(vars["symbol"], interval, 500)
+		if err != nil {
 
+			//stored data is wrong, not request
+			if err == errWrongCandles || err == errSequencesNotUpdated {
+				w.WriteHeader(http.StatusInternalServerError)
 			}
 
-		} else {
-
-			candles, err = manager.GetLastKLinesFromTimestamp(vars["symbol"], interval, timestamp, 500)
-
-		}
-
-		isCorrect := candlescommon.CheckCandles(candles)
-
-		if !isCorrect {
-			log.Fatal(candles)
-		}
-
-		if err != nil {
-
 			w.Write([]byte(err.Error()))
 			return
 		}
 
-		if len(candles) == 0 {
-			w.Write([]byte("Data not exist"))
-			return
-		}
-
-		if !candles[len(candles)-1].Closed || (candles[len(candles)-1].CloseTime >= timestamp) {
-			candles = candles[:len(candles)-1]
-
-		}
+		minValue := bestSequenceList.Front()
 
-		setTime := timestamp
+		if minValue != nil && minValue.Value.(manager.SequenceValue).Sequence != 2 {
+			log.Println("Add value 2:", intervalStr)
+			bestSequenceList.PushFront(manager.SequenceValue{LowCentralPrice: false, Sequence: 2, CentralPrice: 0})
 
-		if timestamp != math.MaxInt64 {
-			setTime = candles[len(candles)-1].OpenTime
 		}
 
-		bestSequenceList, lastUpdate, rsiP, err := manager.GetPeriodsFromDatabase(vars["symbol"], intervalStr, int64(setTime))
+		previousAddedSeq := 0
+		prevRealSeqValue := 0
 
-		if lastUpdate <= candles[0].OpenTime {
-			bestSequenceList, lastUpdate, rsiP, err = manager.GetSequncesWithUpdate(vars["symbol"], interval, int64(setTime))
+		for e := bestSequenceList.Front(); e != nil; e = e.Next() {
+			log.Println(intervalStr, e.Value.(manager.SequenceValue))
 		}
 
-		if err != nil || lastUpdate <= candles[0].OpenTime {
-			log.Fatal(err, lastUpdate, candles[0].OpenTime, timestamp, intervalStr)
-		}
+		for e := bestSequenceList.Front(); e != nil; e = e.Next() {
 
-		lowReverse := indicators.NewRSILowReverseIndicator()
-		lowsMap := indicators.GenerateMapLows(lowReverse, candles)
+			sequenceData := e.Value.(manager.SequenceValue)
 
-		for idx, candle := range candles {
+			t := false
 
-			_, ok := lowsMap[idx]
+			if previousAddedSeq < sequenceData.Sequence {
 
-			if ok && candle.OpenTime > lastUpdate {
+				sign := ""
 
-				bestPeriod, _, centralPrice := rsiP.GetBestPeriod(candle.LowPrice, float64(centralRSI))
+				if prevRealSeqValue+1 == sequenceData.Sequence && prevRealSeqValue > 2 {
+					t = true
+				}
 
-				periods := make([]int, 0)
+				if sequenceData.Count > 1 && sequenceData.Sequence != 2 {
+					sign += "!"
+				}
 
-				up, down, _ := rsiP.GetIntervalForPeriod(bestPeriod, float64(centralRSI))
+				//pivot was beyond zones of all periods
+				if sequenceData.LadderPosition != "" {
+					sign += "#"
+				}
 
-				if bestPeriod > 2 || (bestPeriod == 2 && candle.LowPrice <= up) {
-
-					periods = append(periods, bestPeriod)
-
-					for _, period := range periods {
-
-						sequence := manager.SequenceValue{LowCentralPrice: true, Sequence: period, CentralPrice: centralPrice, Fictive: bestPeriod != period, Timestamp: candle.OpenTime, Central: centralPrice, Lower: candle.LowPrice, Down: down, Count: 1}
-
-						if sequence.Fictive {
-							sequence.Count -= 1
-						}
-						for e := bestSequenceList.Front(); e != nil && e.Value.(manager.SequenceValue).Sequence <= period; e = bestSequenceList.Front() {
-
-							if sequence.Sequence == e.Value.(manager.SequenceValue).Sequence {
-								sequence.Count += e.Value.(manager.SequenceValue).Count
-							}
-
-							bestSequenceList.Remove(e)
-						}
-
-						bestSequenceList.PushFront(sequence)
-					}
-
-				} else {
-
-					bestPeriod = 0
-					up = 0
-					down = 0
-				}
-
-			}
-
-			if candle.OpenTime > lastUpdate {
-				rsiP.AddPoint(candle.ClosePrice)
-			}
-
-		}
-
-		minValue := bestSequenceList.Front()
-
-		if minValue != nil && minValue.Value.(manager.SequenceValue).Sequence != 2 {
-			log.Println("Add value 2:", intervalStr)
-			bestSequenceList.PushFront(manager.SequenceValue{LowCentralPrice: false, Sequence: 2, CentralPrice: 0})
-
-		}
-
-		previousAddedSeq := 0
-		prevRealSeqValue := 0
-
-		for e := bestSequenceList.Front(); e != nil; e = e.Next() {
-			log.Println(intervalStr, e.Value.(manager.SequenceValue))
-		}
-
-		for e := bestSequenceList.Front(); e != nil; e = e.Next() {
-
-			sequenceData := e.Value.(manager.SequenceValue)
-
-			t := false
-
-			if previousAddedSeq < sequenceData.Sequence {
-
-				sign := ""
-
-				if prevRealSeqValue+1 == sequenceData.Sequence && prevRealSeqValue > 2 {
-					t = true
-				}
-
-				if sequenceData.Count > 1 && sequenceData.Sequence != 2 {
-					sign += "!"
-				}
-
-				up, down, _ := rsiP.GetIntervalForPeriod(sequenceData.Sequence, float64(centralRSI))
+				up, down, _ := sequenceZone(rsiP, sequenceData.Sequence, float64(centralRSI), options)
 
 				if up <= down || up <= 0 || down <= 0 {
 					continue
@@ -825,15 +844,19 @@ func NewGroupsHandler(w http.ResponseWriter, r *http.Request) {
 
 				}
 
-				sequenceData.Sequence += 1
 				sequenceData.LowCentralPrice = false
 
 				up, down := float64(0), float64(0)
 
-				if sequenceData.Sequence < 250 {
-					up, down, _ = rsiP.GetIntervalForPeriod(sequenceData.Sequence, float64(centralRSI))
+				//zone of the next period in the ladder, the last period has no zone
+				nextPeriod, ok := rsiP.NextPeriod(sequenceData.Sequence)
+
+				if ok && nextPeriod < rsiP.MaxPeriod() {
+					up, down, _ = sequenceZone(rsiP, nextPeriod, float64(centralRSI), options)
 				}
 
+				sequenceData.Sequence = nextPeriod
+
 				if up <= down || up <= 0 || down <= 0 {
 					continue
 				}
@@ -1008,3 +1031,520 @@ func NewGroupsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(byte)
 
 }
+
+// DivergenceHandler returns RSI divergences between consecutive lows and consecutive highs
+func DivergenceHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	interval := candlescommon.IntervalFromStr(vars["interval"])
+
+	limit := uint64(1000)
+
+	if len(r.URL.Query()["limit"]) > 0 {
+
+		limit, _ = strconv.ParseUint(r.URL.Query()["limit"][0], 10, 64)
+	}
+
+	endTimestamp := uint64(0)
+
+	if len(r.URL.Query()["endTimestamp"]) > 0 {
+
+		endTimestamp, _ = strconv.ParseUint(r.URL.Query()["endTimestamp"][0], 10, 64)
+	}
+
+	maxBars, _ := strconv.ParseUint(r.URL.Query().Get("maxBars"), 10, 64)
+
+	//RSI periods compared on pivots, like 14 or 7,14,21
+	periodsStr := r.URL.Query().Get("periods")
+
+	if periodsStr == "" {
+		periodsStr = "14"
+	}
+
+	periods, err := indicators.ParsePeriodSet(periodsStr)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	rsiSmoothing := r.URL.Query().Get("rsiSmoothing")
+	rsiSource := r.URL.Query().Get("rsiSource")
+
+	err = indicators.CheckRSISettings(rsiSmoothing, rsiSource)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	lowReverse, err := indicators.NewLowDetectorFromString(r.URL.Query().Get("pivots"))
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	var candles []candlescommon.KLine
+
+	if endTimestamp > 0 {
+		candles, err = manager.GetLastKLinesFromTimestamp(vars["symbol"], interval, endTimestamp, int(limit))
+	} else {
+		candles, err = manager.GetLastKLines(vars["symbol"], interval, int(limit))
+	}
+
+	if err != nil {
+
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if len(candles) == 0 {
+		w.Write([]byte("Data not exist"))
+		return
+	}
+
+	rsiP := indicators.NewRSIMultiplePeriodsWithPeriods(periods, rsiSmoothing, rsiSource)
+
+	candlesOld, err := manager.GetRSIWarmUpKLines(vars["symbol"], interval, candles[0].OpenTime, rsiP)
+
+	if err != nil {
+
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	highReverse := indicators.NewRSIHighReverseIndicator()
+
+	if len(candlesOld) > 0 {
+		highReverse.AddPoint(candlesOld[len(candlesOld)-1].HighPrice)
+	}
+
+	indicators.PrimeLowDetector(lowReverse, candlesOld)
+
+	for _, candleOld := range candlesOld {
+		rsiP.AddCandle(candleOld)
+	}
+
+	lowsMap := indicators.GenerateMapLows(lowReverse, candles)
+	highsMap := indicators.GenerateMapHighs(highReverse, candles)
+
+	comparedPeriods := make([]int, 0, len(periods))
+
+	for _, period := range periods {
+		comparedPeriods = append(comparedPeriods, int(period))
+	}
+
+	events := indicators.FindDivergences(candles, lowsMap, highsMap, rsiP, comparedPeriods, int(maxBars))
+
+	byte, err := json.Marshal(events)
+
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	w.Write(byte)
+}
+
+// SequenceAppearanceHandler returns the latest saved sequence of period with count more than minCount, 1 by default
+func SequenceAppearanceHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	centralRSI, _ := strconv.ParseUint(vars["centralRSI"], 10, 64)
+
+	options, err := sequenceOptionsFromRequest(r)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if centralRSI == 0 {
+		centralRSI = uint64(options.DefaultCentralRSI())
+	}
+
+	period, err := strconv.Atoi(vars["period"])
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	minCount := uint64(1)
+
+	if len(r.URL.Query().Get("minCount")) > 0 {
+
+		minCount, err = strconv.ParseUint(r.URL.Query().Get("minCount"), 10, 64)
+
+		if err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	sequence, ok, err := manager.LastSequenceAppearance(vars["symbol"], vars["interval"], uint(centralRSI), options, period, uint(minCount))
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if !ok {
+		w.Write([]byte("Sequence not found"))
+		return
+	}
+
+	byte, err := json.Marshal(sequence)
+
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	w.Write(byte)
+}
+
+// SequenceTimelineHandler returns stack changes on candles from timestamp from to timestamp to, to is now by default
+func SequenceTimelineHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	centralRSI, _ := strconv.ParseUint(vars["centralRSI"], 10, 64)
+
+	options, err := sequenceOptionsFromRequest(r)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if centralRSI == 0 {
+		centralRSI = uint64(options.DefaultCentralRSI())
+	}
+
+	from, err := strconv.ParseUint(r.URL.Query().Get("from"), 10, 64)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	to := uint64(math.MaxInt64)
+
+	if len(r.URL.Query().Get("to")) > 0 {
+
+		to, err = strconv.ParseUint(r.URL.Query().Get("to"), 10, 64)
+
+		if err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	timeline, err := manager.GetSequenceTimeline(vars["symbol"], candlescommon.IntervalFromStr(vars["interval"]), uint(centralRSI), options, from, to)
+
+	if err != nil {
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	byte, err := json.Marshal(timeline)
+
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	w.Write(byte)
+}
+
+// groupSequences returns sequences of options and RSI counted up to the last candle closed before timestamp,
+// the latest sequences of live tracked intervals are taken from memory
+func groupSequences(symbol string, intervalStr string, timestamp uint64, centralRSI uint, options manager.SequenceOptions, barsType string, barsSize float64) (*list.List, *indicators.RSIMultiplePeriods, error) {
+
+	if barsType != "" && barsType != candlescommon.BarsTime {
+		return barsSequences(symbol, intervalStr, timestamp, centralRSI, options, barsType, barsSize)
+	}
+
+	if timestamp == math.MaxInt64 && manager.LiveSequencer != nil {
+
+		if sequences, rsiP, ok := manager.LiveSequencer.Get(symbol, intervalStr, centralRSI, options); ok {
+			return sequences, rsiP, nil
+		}
+	}
+
+	interval := candlescommon.IntervalFromStr(intervalStr)
+
+	candles, err := closedGroupKLines(symbol, interval, timestamp)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	setTime := timestamp
+
+	if timestamp != math.MaxInt64 {
+		setTime = candles[len(candles)-1].OpenTime
+	}
+
+	bestSequenceList, lastUpdate, rsiP, err := manager.GetPeriodsFromDatabase(symbol, intervalStr, centralRSI, options, int64(setTime))
+
+	if lastUpdate <= candles[0].OpenTime {
+		bestSequenceList, lastUpdate, rsiP, err = manager.GetSequncesWithUpdate(symbol, interval, centralRSI, options, int64(setTime))
+	}
+
+	if err != nil || lastUpdate <= candles[0].OpenTime {
+		log.Println("Sequences are not updated: ", err, lastUpdate, candles[0].OpenTime, timestamp, intervalStr)
+		return nil, nil, errSequencesNotUpdated
+	}
+
+	//tracker continues saved sequences with candles after the last update
+	tracker, err := options.NewTracker(rsiP, float64(centralRSI))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracker.Continue(bestSequenceList, lastUpdate)
+
+	for _, candle := range candles {
+
+		if candle.OpenTime > lastUpdate {
+			tracker.Add(candle)
+		} else {
+			tracker.PrimeDetector([]candlescommon.KLine{candle})
+		}
+	}
+
+	return tracker.List(), tracker.RSI, nil
+}
+
+// barsSequences counts sequences on bars built from candles closed before timestamp. Bars depend on all candles
+// they were built from, so their sequences aren't saved and are counted from RSI warm-up on every request
+func barsSequences(symbol string, intervalStr string, timestamp uint64, centralRSI uint, options manager.SequenceOptions, barsType string, barsSize float64) (*list.List, *indicators.RSIMultiplePeriods, error) {
+
+	interval := candlescommon.IntervalFromStr(intervalStr)
+
+	candles, err := closedGroupKLines(symbol, interval, timestamp)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rsiP := indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod)
+
+	candlesOld, err := manager.GetRSIWarmUpKLines(symbol, interval, candles[0].OpenTime, rsiP)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	firstOpenTime := candles[0].OpenTime
+
+	//build bars over old and new candles together, so bars are continuous
+	bars, err := candlescommon.BuildBars(append(candlesOld, candles...), barsType, barsSize)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	//bar that isn't completed yet can't be used for count
+	if len(bars) > 0 && !bars[len(bars)-1].Closed {
+		bars = bars[:len(bars)-1]
+	}
+
+	idx := sort.Search(len(bars), func(i int) bool {
+		return bars[i].OpenTime >= firstOpenTime
+	})
+
+	tracker, err := options.NewTracker(rsiP, float64(centralRSI))
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracker.Prime(bars[:idx])
+
+	for _, bar := range bars[idx:] {
+		tracker.Add(bar)
+	}
+
+	return tracker.List(), tracker.RSI, nil
+}
+
+// closedGroupKLines returns the last candles of groups closed before timestamp, the latest candles are taken from cache
+func closedGroupKLines(symbol string, interval candlescommon.Interval, timestamp uint64) ([]candlescommon.KLine, error) {
+
+	var err error
+	var candles []candlescommon.KLine
+
+	if timestamp == math.MaxInt64 {
+
+		var ok bool
+
+		candles, ok = manager.KLineCacher.GetLatestKLines(symbol, interval)
+
+		if !ok {
+
+			candles, err = manager.GetLastKLines(symbol, interval, 500)
+
+		}
+
+	} else {
+
+		candles, err = manager.GetLastKLinesFromTimestamp(symbol, interval, timestamp, 500)
+
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	isCorrect := candlescommon.CheckCandles(candles)
+
+	if !isCorrect {
+		log.Println("Wrong candles: ", symbol, interval, timestamp, candles)
+		return nil, errWrongCandles
+	}
+
+	if len(candles) == 0 {
+		return nil, errDataNotExist
+	}
+
+	if !candles[len(candles)-1].Closed || (candles[len(candles)-1].CloseTime >= timestamp) {
+		candles = candles[:len(candles)-1]
+
+	}
+
+	if len(candles) == 0 {
+		return nil, errDataNotExist
+	}
+
+	return candles, nil
+}
+
+// sequenceOptionsFromRequest returns pivots counted in sequences, side=high counts highs on resistance zones,
+// pivots like fractal:2:2, zigzag:5 or zigzagatr:14:3 select low detector,
+// lowPatterns=hammer,doji counts only pivots confirmed by pattern with patternConfidence
+func sequenceOptionsFromRequest(r *http.Request) (manager.SequenceOptions, error) {
+
+	options := manager.SequenceOptions{
+		High:   r.URL.Query().Get("side") == "high",
+		Pivots: strings.ToLower(r.URL.Query().Get("pivots")),
+	}
+
+	if len(r.URL.Query().Get("lowPatterns")) > 0 {
+
+		options.Patterns = patterns.ParseNames(r.URL.Query().Get("lowPatterns"))
+		options.PatternConfidence, _ = strconv.ParseFloat(r.URL.Query().Get("patternConfidence"), 64)
+	}
+
+	return options, options.Validate()
+}
+
+// sequenceZone returns zone of period of side of options
+func sequenceZone(rsiP *indicators.RSIMultiplePeriods, period int, centralRSI float64, options manager.SequenceOptions) (float64, float64, float64) {
+
+	if options.High {
+		return rsiP.GetIntervalForPeriodHigh(period, centralRSI)
+	}
+
+	return rsiP.GetIntervalForPeriod(period, centralRSI)
+}
+
+// rsiPeriodsFromRequest returns RSI periods from query like periods=1-50,55-500/5, default ladder is used without it
+func rsiPeriodsFromRequest(r *http.Request) ([]uint, error) {
+
+	if len(r.URL.Query().Get("periods")) == 0 {
+		return indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod).Periods, nil
+	}
+
+	return indicators.ParsePeriodSet(r.URL.Query().Get("periods"))
+}
+
+func ValidateCandlesHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	interval := candlescommon.IntervalFromStr(vars["interval"])
+
+	limit := uint64(1000)
+
+	if len(r.URL.Query()["limit"]) > 0 {
+
+		limit, _ = strconv.ParseUint(r.URL.Query()["limit"][0], 10, 64)
+	}
+
+	endTimestamp := uint64(0)
+
+	if len(r.URL.Query()["endTimestamp"]) > 0 {
+
+		endTimestamp, _ = strconv.ParseUint(r.URL.Query()["endTimestamp"][0], 10, 64)
+	}
+
+	var candles []candlescommon.KLine
+	var err error
+
+	if endTimestamp > 0 {
+		candles, err = manager.GetLastKLinesFromTimestamp(vars["symbol"], interval, endTimestamp, int(limit))
+	} else {
+		candles, err = manager.GetLastKLines(vars["symbol"], interval, int(limit))
+	}
+
+	if err != nil {
+
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	report := candlescommon.ValidateCandles(candles, interval)
+
+	byte, err := json.Marshal(report)
+
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	w.Write(byte)
+}
+
+func IndexDefinitionHandler(w http.ResponseWriter, r *http.Request) {
+
+	vars := mux.Vars(r)
+
+	if r.Method == http.MethodPost {
+
+		var index manager.IndexDefinition
+
+		err := json.NewDecoder(r.Body).Decode(&index)
+
+		if err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		index.Name = vars["name"]
+
+		err = manager.SaveIndexDefinition(index)
+
+		if err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	index, ok := manager.GetIndexDefinition(vars["name"])
+
+	if !ok {
+		w.Write([]byte("Index not exist"))
+		return
+	}
+
+	byte, err := json.Marshal(index)
+
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	w.Write(byte)
+}
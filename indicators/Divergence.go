@@ -0,0 +1,169 @@
+package indicators
+
+import (
+	"github.com/NERON/tran/candlescommon"
+	"math"
+)
+
+const (
+	DivergenceRegularBullish = "regular bullish"
+	DivergenceHiddenBullish  = "hidden bullish"
+	DivergenceRegularBearish = "regular bearish"
+	DivergenceHiddenBearish  = "hidden bearish"
+)
+
+// DivergenceEvent is divergence between two consecutive pivots for one RSI period.
+// Strength is product of RSI change in points and price change in percent
+type DivergenceEvent struct {
+	Type          string
+	Period        int
+	FromTimestamp uint64
+	ToTimestamp   uint64
+	FromPrice     float64
+	ToPrice       float64
+	FromRSI       float64
+	ToRSI         float64
+	PriceChange   float64
+	RSIChange     float64
+	Strength      float64
+}
+
+type divergencePivot struct {
+	Index     int
+	Timestamp uint64
+	Price     float64
+	RSIs      map[int]float64
+}
+
+// DivergenceTracker compares every pivot with the previous pivot of the same side
+type DivergenceTracker struct {
+	Periods []int
+
+	//pivots more than MaxBars candles apart aren't compared, 0 is no limit
+	MaxBars int
+
+	lastLow  *divergencePivot
+	lastHigh *divergencePivot
+}
+
+func (dt *DivergenceTracker) pivot(index int, timestamp uint64, price float64, rsip *RSIMultiplePeriods) *divergencePivot {
+
+	pivot := &divergencePivot{Index: index, Timestamp: timestamp, Price: price, RSIs: make(map[int]float64)}
+
+	for _, period := range dt.Periods {
+
+		if value, ok := rsip.GetRSI(period); ok {
+			pivot.RSIs[period] = value
+		}
+	}
+
+	return pivot
+}
+
+func (dt *DivergenceTracker) compare(previous *divergencePivot, current *divergencePivot, low bool) []DivergenceEvent {
+
+	events := make([]DivergenceEvent, 0)
+
+	if previous == nil || (dt.MaxBars > 0 && current.Index-previous.Index > dt.MaxBars) {
+		return events
+	}
+
+	for _, period := range dt.Periods {
+
+		fromRSI, okFrom := previous.RSIs[period]
+		toRSI, okTo := current.RSIs[period]
+
+		if !okFrom || !okTo {
+			continue
+		}
+
+		divergenceType := ""
+
+		lowerPrice := current.Price < previous.Price
+		higherPrice := current.Price > previous.Price
+		lowerRSI := toRSI < fromRSI
+		higherRSI := toRSI > fromRSI
+
+		if low {
+
+			if lowerPrice && higherRSI {
+				divergenceType = DivergenceRegularBullish
+			} else if higherPrice && lowerRSI {
+				divergenceType = DivergenceHiddenBullish
+			}
+
+		} else {
+
+			if higherPrice && lowerRSI {
+				divergenceType = DivergenceRegularBearish
+			} else if lowerPrice && higherRSI {
+				divergenceType = DivergenceHiddenBearish
+			}
+		}
+
+		if divergenceType == "" {
+			continue
+		}
+
+		priceChange := (current.Price/previous.Price - 1) * 100
+
+		events = append(events, DivergenceEvent{
+			Type:          divergenceType,
+			Period:        period,
+			FromTimestamp: previous.Timestamp,
+			ToTimestamp:   current.Timestamp,
+			FromPrice:     previous.Price,
+			ToPrice:       current.Price,
+			FromRSI:       fromRSI,
+			ToRSI:         toRSI,
+			PriceChange:   priceChange,
+			RSIChange:     toRSI - fromRSI,
+			Strength:      math.Abs(toRSI-fromRSI) * math.Abs(priceChange),
+		})
+	}
+
+	return events
+}
+
+// AddLow compares low with previous low, rsip must already contain the low candle
+func (dt *DivergenceTracker) AddLow(index int, kline candlescommon.KLine, rsip *RSIMultiplePeriods) []DivergenceEvent {
+
+	current := dt.pivot(index, kline.OpenTime, kline.LowPrice, rsip)
+	events := dt.compare(dt.lastLow, current, true)
+	dt.lastLow = current
+
+	return events
+}
+
+// AddHigh compares high with previous high, rsip must already contain the high candle
+func (dt *DivergenceTracker) AddHigh(index int, kline candlescommon.KLine, rsip *RSIMultiplePeriods) []DivergenceEvent {
+
+	current := dt.pivot(index, kline.OpenTime, kline.HighPrice, rsip)
+	events := dt.compare(dt.lastHigh, current, false)
+	dt.lastHigh = current
+
+	return events
+}
+
+// FindDivergences feeds candles to rsip and returns divergences between consecutive lows and consecutive highs,
+// maps of pivots are results of GenerateMapLows and GenerateMapHighs for the same candles
+func FindDivergences(candles []candlescommon.KLine, lowsMap map[int]struct{}, highsMap map[int]struct{}, rsip *RSIMultiplePeriods, periods []int, maxBars int) []DivergenceEvent {
+
+	tracker := &DivergenceTracker{Periods: periods, MaxBars: maxBars}
+	events := make([]DivergenceEvent, 0)
+
+	for idx, candle := range candles {
+
+		rsip.AddCandle(candle)
+
+		if _, ok := lowsMap[idx]; ok {
+			events = append(events, tracker.AddLow(idx, candle, rsip)...)
+		}
+
+		if _, ok := highsMap[idx]; ok {
+			events = append(events, tracker.AddHigh(idx, candle, rsip)...)
+		}
+	}
+
+	return events
+}
@@ -0,0 +1,255 @@
+package indicators
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var errWrongIndicatorType = errors.New("stored state belongs to another indicator")
+var errUnsupportedVersion = errors.New("stored state version is not supported")
+var errWrongSnapshot = errors.New("snapshot belongs to another indicator")
+var errNotLowDetector = errors.New("indicator can't detect lows")
+
+// MaxIndicatorPeriod is the longest period of indicator created from description
+const MaxIndicatorPeriod = 10000
+
+// Indicator is common interface for all incremental indicators
+type Indicator interface {
+	AddPoint(value float64)
+	Value() (float64, bool)
+	Clone() Indicator
+	Snapshot() IndicatorSnapshot
+	Restore(snapshot IndicatorSnapshot) error
+	Marshal() ([]byte, error)
+	Unmarshal(data []byte) error
+}
+
+// MultipleValuesIndicator is implemented by indicators that have additional lines besides main value
+type MultipleValuesIndicator interface {
+	Values() (map[string]float64, bool)
+}
+
+// IndicatorSnapshot is opaque copy of indicator state, it can be restored only by the same indicator type
+type IndicatorSnapshot interface{}
+
+// IndicatorState is versioned envelope used for persistence
+type IndicatorState struct {
+	Type    string
+	Version int
+	Data    json.RawMessage
+}
+
+func marshalState(indicatorType string, version int, data interface{}) ([]byte, error) {
+
+	raw, err := json.Marshal(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(IndicatorState{Type: indicatorType, Version: version, Data: raw})
+}
+
+func unmarshalState(data []byte, indicatorType string, version int, target interface{}) error {
+
+	var state IndicatorState
+
+	err := json.Unmarshal(data, &state)
+
+	if err != nil {
+		return err
+	}
+
+	//data that was saved before versioning has no envelope
+	if state.Type == "" {
+		return json.Unmarshal(data, target)
+	}
+
+	if state.Type != indicatorType {
+		return errWrongIndicatorType
+	}
+
+	if state.Version > version {
+		return errUnsupportedVersion
+	}
+
+	return json.Unmarshal(state.Data, target)
+}
+
+var indicatorFactories = make(map[string]func(params []float64) (Indicator, error))
+var factoriesMu = &sync.RWMutex{}
+
+// RegisterIndicator makes indicator available by name for requests and stored states
+func RegisterIndicator(indicatorType string, factory func(params []float64) (Indicator, error)) {
+
+	factoriesMu.Lock()
+	indicatorFactories[strings.ToLower(indicatorType)] = factory
+	factoriesMu.Unlock()
+}
+
+func newIndicator(indicatorType string, params []float64) (Indicator, error) {
+
+	factoriesMu.RLock()
+	factory, ok := indicatorFactories[strings.ToLower(indicatorType)]
+	factoriesMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown indicator %s", indicatorType)
+	}
+
+	return factory(params)
+}
+
+// NewIndicatorFromString creates indicator from description like rsi:14
+func NewIndicatorFromString(description string) (Indicator, error) {
+
+	parts := strings.Split(description, ":")
+	params := make([]float64, 0, len(parts)-1)
+
+	for _, part := range parts[1:] {
+
+		param, err := strconv.ParseFloat(part, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		params = append(params, param)
+	}
+
+	return newIndicator(parts[0], params)
+}
+
+// NewIndicatorFromState restores indicator of any registered type from Marshal result
+func NewIndicatorFromState(data []byte) (Indicator, error) {
+
+	var state IndicatorState
+
+	err := json.Unmarshal(data, &state)
+
+	if err != nil {
+		return nil, err
+	}
+
+	indicator, err := newIndicator(state.Type, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = indicator.Unmarshal(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return indicator, nil
+}
+
+func paramOrDefault(params []float64, idx int, defaultValue float64) float64 {
+
+	if idx < len(params) {
+		return params[idx]
+	}
+
+	return defaultValue
+}
+
+// periodParam returns period at idx or defaultValue, period should be integer from 1 to MaxIndicatorPeriod
+func periodParam(params []float64, idx int, defaultValue uint) (uint, error) {
+
+	value := paramOrDefault(params, idx, float64(defaultValue))
+
+	//NaN doesn't pass comparison
+	if !(value >= 1 && value <= MaxIndicatorPeriod) || value != math.Trunc(value) {
+		return 0, fmt.Errorf("indicator period should be integer from 1 to %d, got %v", MaxIndicatorPeriod, value)
+	}
+
+	return uint(value), nil
+}
+
+// periodParams returns periods with defaults for all of them
+func periodParams(params []float64, defaultValues ...uint) ([]uint, error) {
+
+	periods := make([]uint, len(defaultValues))
+
+	for idx, defaultValue := range defaultValues {
+
+		period, err := periodParam(params, idx, defaultValue)
+
+		if err != nil {
+			return nil, err
+		}
+
+		periods[idx] = period
+	}
+
+	return periods, nil
+}
+
+// positiveParam returns finite positive parameter at idx or defaultValue
+func positiveParam(params []float64, idx int, defaultValue float64) (float64, error) {
+
+	value := paramOrDefault(params, idx, defaultValue)
+
+	if !(value > 0) || math.IsInf(value, 1) {
+		return 0, fmt.Errorf("indicator parameter should be positive, got %v", value)
+	}
+
+	return value, nil
+}
+
+// timeParam returns timestamp or duration in milliseconds at idx or defaultValue
+func timeParam(params []float64, idx int, defaultValue uint64) (uint64, error) {
+
+	value := paramOrDefault(params, idx, float64(defaultValue))
+
+	if !(value >= 0 && value <= math.MaxInt64) || value != math.Trunc(value) {
+		return 0, fmt.Errorf("indicator time should be milliseconds, got %v", value)
+	}
+
+	return uint64(value), nil
+}
+
+func init() {
+
+	for smoothingType, smoothing := range map[string]string{"RSI": "", "RSIEMA": RSISmoothingEMA, "RSISMA": RSISmoothingSMA} {
+
+		rsiSmoothing := smoothing
+
+		RegisterIndicator(smoothingType, func(params []float64) (Indicator, error) {
+
+			period, err := periodParam(params, 0, 14)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return &RSI{Period: period, Smoothing: rsiSmoothing}, nil
+		})
+	}
+
+	RegisterIndicator("RSIMultiplePeriods", func(params []float64) (Indicator, error) {
+
+		maxPeriod, err := periodParam(params, 0, DefaultMaxPeriod)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewRSIMultiplePeriods(int(maxPeriod)), nil
+	})
+
+	RegisterIndicator("RSILowReverse", func(params []float64) (Indicator, error) {
+		return NewRSILowReverseIndicator(), nil
+	})
+
+	RegisterIndicator("RSIHighReverse", func(params []float64) (Indicator, error) {
+		return NewRSIHighReverseIndicator(), nil
+	})
+}
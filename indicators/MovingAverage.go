@@ -0,0 +1,329 @@
+package indicators
+
+import (
+	"math"
+)
+
+const (
+	MovingAverageSMA = "SMA"
+	MovingAverageEMA = "EMA"
+	MovingAverageWMA = "WMA"
+	MovingAverageRMA = "RMA"
+	MovingAverageHMA = "HMA"
+)
+
+type MovingAverage struct {
+	Kind   string
+	Period uint
+
+	PointsCount uint
+
+	//last points for window based averages
+	Window []float64
+	Sum    float64
+
+	//current value for exponential averages
+	Average float64
+
+	//inner averages for Hull moving average
+	Half *MovingAverage `json:",omitempty"`
+	Full *MovingAverage `json:",omitempty"`
+	Hull *MovingAverage `json:",omitempty"`
+}
+
+func (ma *MovingAverage) AddPoint(value float64) {
+
+	ma.PointsCount++
+
+	switch ma.Kind {
+
+	case MovingAverageSMA, MovingAverageWMA:
+
+		ma.Window = append(ma.Window, value)
+		ma.Sum += value
+
+		if len(ma.Window) > int(ma.Period) {
+			ma.Sum -= ma.Window[0]
+			ma.Window = ma.Window[1:]
+		}
+
+	case MovingAverageEMA, MovingAverageRMA:
+
+		if ma.PointsCount <= ma.Period {
+
+			ma.Sum += value
+
+			//first value is simple average
+			if ma.PointsCount == ma.Period {
+				ma.Average = ma.Sum / float64(ma.Period)
+			}
+
+		} else {
+
+			ma.Average += ma.alpha() * (value - ma.Average)
+		}
+
+	case MovingAverageHMA:
+
+		ma.Half.AddPoint(value)
+		ma.Full.AddPoint(value)
+
+		half, okHalf := ma.Half.Calculate()
+		full, okFull := ma.Full.Calculate()
+
+		if okHalf && okFull {
+			ma.Hull.AddPoint(2*half - full)
+		}
+	}
+}
+
+func (ma *MovingAverage) alpha() float64 {
+
+	if ma.Kind == MovingAverageRMA {
+		return 1 / float64(ma.Period)
+	}
+
+	return 2 / float64(ma.Period+1)
+}
+
+func (ma *MovingAverage) Calculate() (float64, bool) {
+
+	switch ma.Kind {
+
+	case MovingAverageSMA:
+
+		if ma.PointsCount < ma.Period {
+			return 0, false
+		}
+
+		return ma.Sum / float64(ma.Period), true
+
+	case MovingAverageWMA:
+
+		if ma.PointsCount < ma.Period {
+			return 0, false
+		}
+
+		weighted := 0.0
+
+		for idx, value := range ma.Window {
+			weighted += float64(idx+1) * value
+		}
+
+		return weighted / float64(ma.Period*(ma.Period+1)/2), true
+
+	case MovingAverageEMA, MovingAverageRMA:
+
+		if ma.PointsCount < ma.Period {
+			return 0, false
+		}
+
+		return ma.Average, true
+
+	case MovingAverageHMA:
+
+		return ma.Hull.Calculate()
+	}
+
+	return 0, false
+}
+
+func (ma *MovingAverage) Value() (float64, bool) {
+	return ma.Calculate()
+}
+
+func (ma *MovingAverage) clone() *MovingAverage {
+
+	if ma == nil {
+		return nil
+	}
+
+	clone := *ma
+
+	clone.Window = make([]float64, len(ma.Window), ma.Period)
+	copy(clone.Window, ma.Window)
+
+	clone.Half = ma.Half.clone()
+	clone.Full = ma.Full.clone()
+	clone.Hull = ma.Hull.clone()
+
+	return &clone
+}
+
+func (ma *MovingAverage) Clone() Indicator {
+	return ma.clone()
+}
+
+func (ma *MovingAverage) Snapshot() IndicatorSnapshot {
+	return ma.clone()
+}
+
+func (ma *MovingAverage) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*MovingAverage)
+
+	if !ok || state.Kind != ma.Kind {
+		return errWrongSnapshot
+	}
+
+	*ma = *state.clone()
+
+	return nil
+}
+
+func (ma *MovingAverage) Marshal() ([]byte, error) {
+	return marshalState("MovingAverage", 1, ma)
+}
+
+func (ma *MovingAverage) Unmarshal(data []byte) error {
+	return unmarshalState(data, "MovingAverage", 1, ma)
+}
+
+func NewMovingAverage(kind string, period uint) *MovingAverage {
+
+	if period == 0 {
+		period = 1
+	}
+
+	ma := &MovingAverage{Kind: kind, Period: period}
+
+	if kind == MovingAverageHMA {
+
+		halfPeriod := period / 2
+
+		if halfPeriod == 0 {
+			halfPeriod = 1
+		}
+
+		ma.Half = NewMovingAverage(MovingAverageWMA, halfPeriod)
+		ma.Full = NewMovingAverage(MovingAverageWMA, period)
+		ma.Hull = NewMovingAverage(MovingAverageWMA, uint(math.Round(math.Sqrt(float64(period)))))
+	}
+
+	return ma
+}
+
+func NewSMA(period uint) *MovingAverage {
+	return NewMovingAverage(MovingAverageSMA, period)
+}
+
+func NewEMA(period uint) *MovingAverage {
+	return NewMovingAverage(MovingAverageEMA, period)
+}
+
+func NewWMA(period uint) *MovingAverage {
+	return NewMovingAverage(MovingAverageWMA, period)
+}
+
+func NewRMA(period uint) *MovingAverage {
+	return NewMovingAverage(MovingAverageRMA, period)
+}
+
+func NewHMA(period uint) *MovingAverage {
+	return NewMovingAverage(MovingAverageHMA, period)
+}
+
+type MovingAverageMultiplePeriods struct {
+	MAs []MovingAverage
+}
+
+func (mam *MovingAverageMultiplePeriods) AddPoint(addPrice float64) {
+
+	for i := 0; i < len(mam.MAs); i++ {
+
+		mam.MAs[i].AddPoint(addPrice)
+	}
+}
+
+// GetValue returns moving average value for period
+func (mam *MovingAverageMultiplePeriods) GetValue(period int) (float64, bool) {
+
+	if period < 1 || period > len(mam.MAs) {
+		return 0, false
+	}
+
+	return mam.MAs[period-1].Calculate()
+}
+
+// Value returns moving average for the largest period
+func (mam *MovingAverageMultiplePeriods) Value() (float64, bool) {
+	return mam.GetValue(len(mam.MAs))
+}
+
+func (mam *MovingAverageMultiplePeriods) clone() *MovingAverageMultiplePeriods {
+
+	MAs := make([]MovingAverage, len(mam.MAs))
+
+	for i := 0; i < len(mam.MAs); i++ {
+		MAs[i] = *mam.MAs[i].clone()
+	}
+
+	return &MovingAverageMultiplePeriods{MAs: MAs}
+}
+
+func (mam *MovingAverageMultiplePeriods) Clone() Indicator {
+	return mam.clone()
+}
+
+func (mam *MovingAverageMultiplePeriods) Snapshot() IndicatorSnapshot {
+	return mam.clone()
+}
+
+func (mam *MovingAverageMultiplePeriods) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*MovingAverageMultiplePeriods)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*mam = *state.clone()
+
+	return nil
+}
+
+func (mam *MovingAverageMultiplePeriods) Marshal() ([]byte, error) {
+	return marshalState("MovingAverageMultiplePeriods", 1, mam)
+}
+
+func (mam *MovingAverageMultiplePeriods) Unmarshal(data []byte) error {
+	return unmarshalState(data, "MovingAverageMultiplePeriods", 1, mam)
+}
+
+func NewMovingAverageMultiplePeriods(kind string, maxPeriod int) *MovingAverageMultiplePeriods {
+
+	MAs := make([]MovingAverage, maxPeriod)
+
+	for i := 0; i < maxPeriod; i++ {
+		MAs[i] = *NewMovingAverage(kind, uint(i+1))
+	}
+
+	return &MovingAverageMultiplePeriods{MAs: MAs}
+}
+
+func init() {
+
+	for _, kind := range []string{MovingAverageSMA, MovingAverageEMA, MovingAverageWMA, MovingAverageRMA, MovingAverageHMA} {
+
+		maKind := kind
+
+		RegisterIndicator(maKind, func(params []float64) (Indicator, error) {
+
+			period, err := periodParam(params, 0, 20)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return NewMovingAverage(maKind, period), nil
+		})
+	}
+
+	RegisterIndicator("MovingAverage", func(params []float64) (Indicator, error) {
+		return &MovingAverage{}, nil
+	})
+
+	RegisterIndicator("MovingAverageMultiplePeriods", func(params []float64) (Indicator, error) {
+		return &MovingAverageMultiplePeriods{}, nil
+	})
+}
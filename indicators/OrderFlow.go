@@ -0,0 +1,329 @@
+package indicators
+
+import (
+	"github.com/NERON/tran/candlescommon"
+)
+
+// candleDelta returns taker buy volume minus taker sell volume
+func candleDelta(kline candlescommon.KLine) float64 {
+	return 2*kline.TakerBuyBaseVolume - kline.BaseVolume
+}
+
+// VolumeDelta is delta of the last candle, if Cumulative is set it's cumulative volume delta
+type VolumeDelta struct {
+	Cumulative  bool
+	Delta       float64
+	PointsCount uint
+}
+
+func (vd *VolumeDelta) AddCandle(kline candlescommon.KLine) {
+
+	if vd.Cumulative {
+		vd.Delta += candleDelta(kline)
+	} else {
+		vd.Delta = candleDelta(kline)
+	}
+
+	vd.PointsCount++
+}
+
+// AddPoint does nothing, delta can be calculated only from candle volumes
+func (vd *VolumeDelta) AddPoint(value float64) {
+}
+
+func (vd *VolumeDelta) Value() (float64, bool) {
+	return vd.Delta, vd.PointsCount > 0
+}
+
+func (vd *VolumeDelta) Clone() Indicator {
+
+	clone := *vd
+
+	return &clone
+}
+
+func (vd *VolumeDelta) Snapshot() IndicatorSnapshot {
+	return *vd
+}
+
+func (vd *VolumeDelta) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(VolumeDelta)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*vd = state
+
+	return nil
+}
+
+func (vd *VolumeDelta) Marshal() ([]byte, error) {
+	return marshalState("VolumeDelta", 1, vd)
+}
+
+func (vd *VolumeDelta) Unmarshal(data []byte) error {
+	return unmarshalState(data, "VolumeDelta", 1, vd)
+}
+
+// TakerBuyRatio is part of volume bought by takers, averaged over Period candles
+type TakerBuyRatio struct {
+	Average *MovingAverage
+}
+
+func (tbr *TakerBuyRatio) AddCandle(kline candlescommon.KLine) {
+
+	//candle without volume is neutral
+	ratio := 0.5
+
+	if kline.BaseVolume > 0 {
+		ratio = kline.TakerBuyBaseVolume / kline.BaseVolume
+	}
+
+	tbr.Average.AddPoint(ratio)
+}
+
+// AddPoint does nothing, ratio can be calculated only from candle volumes
+func (tbr *TakerBuyRatio) AddPoint(value float64) {
+}
+
+func (tbr *TakerBuyRatio) Value() (float64, bool) {
+	return tbr.Average.Calculate()
+}
+
+func (tbr *TakerBuyRatio) clone() *TakerBuyRatio {
+	return &TakerBuyRatio{Average: tbr.Average.clone()}
+}
+
+func (tbr *TakerBuyRatio) Clone() Indicator {
+	return tbr.clone()
+}
+
+func (tbr *TakerBuyRatio) Snapshot() IndicatorSnapshot {
+	return tbr.clone()
+}
+
+func (tbr *TakerBuyRatio) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*TakerBuyRatio)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*tbr = *state.clone()
+
+	return nil
+}
+
+func (tbr *TakerBuyRatio) Marshal() ([]byte, error) {
+	return marshalState("TakerBuyRatio", 1, tbr)
+}
+
+func (tbr *TakerBuyRatio) Unmarshal(data []byte) error {
+	return unmarshalState(data, "TakerBuyRatio", 1, tbr)
+}
+
+func NewTakerBuyRatio(period uint) *TakerBuyRatio {
+	return &TakerBuyRatio{Average: NewSMA(period)}
+}
+
+// OBV is on balance volume
+type OBV struct {
+	Volume      float64
+	PrevClose   float64
+	PointsCount uint
+}
+
+func (obv *OBV) AddCandle(kline candlescommon.KLine) {
+
+	if obv.PointsCount > 0 {
+
+		if kline.ClosePrice > obv.PrevClose {
+			obv.Volume += kline.BaseVolume
+		} else if kline.ClosePrice < obv.PrevClose {
+			obv.Volume -= kline.BaseVolume
+		}
+	}
+
+	obv.PrevClose = kline.ClosePrice
+	obv.PointsCount++
+}
+
+// AddPoint does nothing, OBV can be calculated only from candle volumes
+func (obv *OBV) AddPoint(value float64) {
+}
+
+func (obv *OBV) Value() (float64, bool) {
+	return obv.Volume, obv.PointsCount > 1
+}
+
+func (obv *OBV) Clone() Indicator {
+
+	clone := *obv
+
+	return &clone
+}
+
+func (obv *OBV) Snapshot() IndicatorSnapshot {
+	return *obv
+}
+
+func (obv *OBV) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(OBV)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*obv = state
+
+	return nil
+}
+
+func (obv *OBV) Marshal() ([]byte, error) {
+	return marshalState("OBV", 1, obv)
+}
+
+func (obv *OBV) Unmarshal(data []byte) error {
+	return unmarshalState(data, "OBV", 1, obv)
+}
+
+// VWAP is volume weighted average price, it resets every ResetInterval milliseconds
+// or it's anchored to AnchorTime when ResetInterval is 0
+type VWAP struct {
+	AnchorTime    uint64
+	ResetInterval uint64
+
+	QuoteVolume float64
+	BaseVolume  float64
+	Session     uint64
+}
+
+func (vwap *VWAP) AddCandle(kline candlescommon.KLine) {
+
+	if kline.OpenTime < vwap.AnchorTime {
+		return
+	}
+
+	if vwap.ResetInterval > 0 && kline.OpenTime/vwap.ResetInterval != vwap.Session {
+
+		vwap.Session = kline.OpenTime / vwap.ResetInterval
+		vwap.QuoteVolume = 0
+		vwap.BaseVolume = 0
+	}
+
+	vwap.QuoteVolume += kline.QuoteVolume
+	vwap.BaseVolume += kline.BaseVolume
+}
+
+// AddPoint does nothing, VWAP can be calculated only from candle volumes
+func (vwap *VWAP) AddPoint(value float64) {
+}
+
+func (vwap *VWAP) Value() (float64, bool) {
+
+	if vwap.BaseVolume == 0 {
+		return 0, false
+	}
+
+	return vwap.QuoteVolume / vwap.BaseVolume, true
+}
+
+func (vwap *VWAP) Clone() Indicator {
+
+	clone := *vwap
+
+	return &clone
+}
+
+func (vwap *VWAP) Snapshot() IndicatorSnapshot {
+	return *vwap
+}
+
+func (vwap *VWAP) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(VWAP)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*vwap = state
+
+	return nil
+}
+
+func (vwap *VWAP) Marshal() ([]byte, error) {
+	return marshalState("VWAP", 1, vwap)
+}
+
+func (vwap *VWAP) Unmarshal(data []byte) error {
+	return unmarshalState(data, "VWAP", 1, vwap)
+}
+
+func NewVWAP(resetInterval uint64) *VWAP {
+	return &VWAP{ResetInterval: resetInterval}
+}
+
+func NewAnchoredVWAP(anchorTime uint64) *VWAP {
+	return &VWAP{AnchorTime: anchorTime}
+}
+
+func init() {
+
+	RegisterIndicator("Delta", func(params []float64) (Indicator, error) {
+		return &VolumeDelta{}, nil
+	})
+
+	RegisterIndicator("CVD", func(params []float64) (Indicator, error) {
+		return &VolumeDelta{Cumulative: true}, nil
+	})
+
+	RegisterIndicator("VolumeDelta", func(params []float64) (Indicator, error) {
+		return &VolumeDelta{}, nil
+	})
+
+	for _, name := range []string{"TakerBuyRatio", "BuyRatio"} {
+
+		RegisterIndicator(name, func(params []float64) (Indicator, error) {
+
+			period, err := periodParam(params, 0, 1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return NewTakerBuyRatio(period), nil
+		})
+	}
+
+	RegisterIndicator("OBV", func(params []float64) (Indicator, error) {
+		return &OBV{}, nil
+	})
+
+	//daily session by default
+	RegisterIndicator("VWAP", func(params []float64) (Indicator, error) {
+
+		resetInterval, err := timeParam(params, 0, 24*60*60*1000)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewVWAP(resetInterval), nil
+	})
+
+	RegisterIndicator("AVWAP", func(params []float64) (Indicator, error) {
+
+		anchorTime, err := timeParam(params, 0, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewAnchoredVWAP(anchorTime), nil
+	})
+}
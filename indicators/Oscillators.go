@@ -0,0 +1,389 @@
+package indicators
+
+import (
+	"github.com/NERON/tran/candlescommon"
+	"math"
+)
+
+// MACD is difference between fast and slow exponential averages with signal line
+type MACD struct {
+	Fast   *MovingAverage
+	Slow   *MovingAverage
+	Signal *MovingAverage
+}
+
+func (macd *MACD) AddPoint(value float64) {
+
+	macd.Fast.AddPoint(value)
+	macd.Slow.AddPoint(value)
+
+	if line, ok := macd.Value(); ok {
+		macd.Signal.AddPoint(line)
+	}
+}
+
+func (macd *MACD) Value() (float64, bool) {
+
+	fast, okFast := macd.Fast.Calculate()
+	slow, okSlow := macd.Slow.Calculate()
+
+	if !okFast || !okSlow {
+		return 0, false
+	}
+
+	return fast - slow, true
+}
+
+func (macd *MACD) Values() (map[string]float64, bool) {
+
+	line, _ := macd.Value()
+	signal, ok := macd.Signal.Calculate()
+
+	if !ok {
+		return nil, false
+	}
+
+	return map[string]float64{"signal": signal, "histogram": line - signal}, true
+}
+
+func (macd *MACD) clone() *MACD {
+	return &MACD{Fast: macd.Fast.clone(), Slow: macd.Slow.clone(), Signal: macd.Signal.clone()}
+}
+
+func (macd *MACD) Clone() Indicator {
+	return macd.clone()
+}
+
+func (macd *MACD) Snapshot() IndicatorSnapshot {
+	return macd.clone()
+}
+
+func (macd *MACD) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*MACD)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*macd = *state.clone()
+
+	return nil
+}
+
+func (macd *MACD) Marshal() ([]byte, error) {
+	return marshalState("MACD", 1, macd)
+}
+
+func (macd *MACD) Unmarshal(data []byte) error {
+	return unmarshalState(data, "MACD", 1, macd)
+}
+
+func NewMACD(fastPeriod uint, slowPeriod uint, signalPeriod uint) *MACD {
+	return &MACD{Fast: NewEMA(fastPeriod), Slow: NewEMA(slowPeriod), Signal: NewEMA(signalPeriod)}
+}
+
+// Stochastic is position of close in high-low range of last Period candles, smoothed to %K and %D
+type Stochastic struct {
+	Period uint
+	Highs  []float64
+	Lows   []float64
+	K      *MovingAverage
+	D      *MovingAverage
+}
+
+func (stoch *Stochastic) addValues(high float64, low float64, close float64) {
+
+	stoch.Highs = append(stoch.Highs, high)
+	stoch.Lows = append(stoch.Lows, low)
+
+	if len(stoch.Highs) > int(stoch.Period) {
+		stoch.Highs = stoch.Highs[1:]
+		stoch.Lows = stoch.Lows[1:]
+	}
+
+	if len(stoch.Highs) < int(stoch.Period) {
+		return
+	}
+
+	maxHigh := stoch.Highs[0]
+	minLow := stoch.Lows[0]
+
+	for i := 1; i < len(stoch.Highs); i++ {
+		maxHigh = math.Max(maxHigh, stoch.Highs[i])
+		minLow = math.Min(minLow, stoch.Lows[i])
+	}
+
+	//flat range hasn't position, take middle
+	raw := 50.0
+
+	if maxHigh > minLow {
+		raw = 100 * (close - minLow) / (maxHigh - minLow)
+	}
+
+	stoch.K.AddPoint(raw)
+
+	if k, ok := stoch.K.Calculate(); ok {
+		stoch.D.AddPoint(k)
+	}
+}
+
+func (stoch *Stochastic) AddCandle(kline candlescommon.KLine) {
+	stoch.addValues(kline.HighPrice, kline.LowPrice, kline.ClosePrice)
+}
+
+func (stoch *Stochastic) AddPoint(value float64) {
+	stoch.addValues(value, value, value)
+}
+
+func (stoch *Stochastic) Value() (float64, bool) {
+	return stoch.K.Calculate()
+}
+
+func (stoch *Stochastic) Values() (map[string]float64, bool) {
+
+	d, ok := stoch.D.Calculate()
+
+	if !ok {
+		return nil, false
+	}
+
+	return map[string]float64{"d": d}, true
+}
+
+func (stoch *Stochastic) clone() *Stochastic {
+
+	clone := &Stochastic{Period: stoch.Period, K: stoch.K.clone(), D: stoch.D.clone()}
+
+	clone.Highs = make([]float64, len(stoch.Highs), stoch.Period)
+	clone.Lows = make([]float64, len(stoch.Lows), stoch.Period)
+
+	copy(clone.Highs, stoch.Highs)
+	copy(clone.Lows, stoch.Lows)
+
+	return clone
+}
+
+func (stoch *Stochastic) Clone() Indicator {
+	return stoch.clone()
+}
+
+func (stoch *Stochastic) Snapshot() IndicatorSnapshot {
+	return stoch.clone()
+}
+
+func (stoch *Stochastic) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*Stochastic)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*stoch = *state.clone()
+
+	return nil
+}
+
+func (stoch *Stochastic) Marshal() ([]byte, error) {
+	return marshalState("Stochastic", 1, stoch)
+}
+
+func (stoch *Stochastic) Unmarshal(data []byte) error {
+	return unmarshalState(data, "Stochastic", 1, stoch)
+}
+
+func NewStochastic(period uint, smoothK uint, smoothD uint) *Stochastic {
+	return &Stochastic{Period: period, K: NewSMA(smoothK), D: NewSMA(smoothD)}
+}
+
+// StochasticRSI is stochastic calculated over RSI values
+type StochasticRSI struct {
+	RSI   *RSI
+	Stoch *Stochastic
+}
+
+func (srsi *StochasticRSI) AddPoint(value float64) {
+
+	srsi.RSI.AddPoint(value)
+
+	if rsiValue, ok := srsi.RSI.Calculate(); ok {
+		srsi.Stoch.addValues(rsiValue, rsiValue, rsiValue)
+	}
+}
+
+func (srsi *StochasticRSI) Value() (float64, bool) {
+	return srsi.Stoch.Value()
+}
+
+func (srsi *StochasticRSI) Values() (map[string]float64, bool) {
+	return srsi.Stoch.Values()
+}
+
+func (srsi *StochasticRSI) clone() *StochasticRSI {
+
+	rsi := *srsi.RSI
+
+	return &StochasticRSI{RSI: &rsi, Stoch: srsi.Stoch.clone()}
+}
+
+func (srsi *StochasticRSI) Clone() Indicator {
+	return srsi.clone()
+}
+
+func (srsi *StochasticRSI) Snapshot() IndicatorSnapshot {
+	return srsi.clone()
+}
+
+func (srsi *StochasticRSI) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*StochasticRSI)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*srsi = *state.clone()
+
+	return nil
+}
+
+func (srsi *StochasticRSI) Marshal() ([]byte, error) {
+	return marshalState("StochasticRSI", 1, srsi)
+}
+
+func (srsi *StochasticRSI) Unmarshal(data []byte) error {
+	return unmarshalState(data, "StochasticRSI", 1, srsi)
+}
+
+func NewStochasticRSI(rsiPeriod uint, stochPeriod uint, smoothK uint, smoothD uint) *StochasticRSI {
+	return &StochasticRSI{RSI: &RSI{Period: rsiPeriod}, Stoch: NewStochastic(stochPeriod, smoothK, smoothD)}
+}
+
+// CCI is commodity channel index over typical price
+type CCI struct {
+	Average *MovingAverage
+	Typical float64
+}
+
+func (cci *CCI) AddCandle(kline candlescommon.KLine) {
+
+	cci.Typical = (kline.HighPrice + kline.LowPrice + kline.ClosePrice) / 3
+	cci.Average.AddPoint(cci.Typical)
+}
+
+func (cci *CCI) AddPoint(value float64) {
+
+	cci.Typical = value
+	cci.Average.AddPoint(value)
+}
+
+func (cci *CCI) Value() (float64, bool) {
+
+	average, ok := cci.Average.Calculate()
+
+	if !ok {
+		return 0, false
+	}
+
+	meanDeviation := 0.0
+
+	for _, value := range cci.Average.Window {
+		meanDeviation += math.Abs(value - average)
+	}
+
+	meanDeviation /= float64(len(cci.Average.Window))
+
+	if meanDeviation == 0 {
+		return 0, true
+	}
+
+	return (cci.Typical - average) / (0.015 * meanDeviation), true
+}
+
+func (cci *CCI) clone() *CCI {
+	return &CCI{Average: cci.Average.clone(), Typical: cci.Typical}
+}
+
+func (cci *CCI) Clone() Indicator {
+	return cci.clone()
+}
+
+func (cci *CCI) Snapshot() IndicatorSnapshot {
+	return cci.clone()
+}
+
+func (cci *CCI) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*CCI)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*cci = *state.clone()
+
+	return nil
+}
+
+func (cci *CCI) Marshal() ([]byte, error) {
+	return marshalState("CCI", 1, cci)
+}
+
+func (cci *CCI) Unmarshal(data []byte) error {
+	return unmarshalState(data, "CCI", 1, cci)
+}
+
+func NewCCI(period uint) *CCI {
+	return &CCI{Average: NewSMA(period)}
+}
+
+func init() {
+
+	RegisterIndicator("MACD", func(params []float64) (Indicator, error) {
+
+		periods, err := periodParams(params, 12, 26, 9)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewMACD(periods[0], periods[1], periods[2]), nil
+	})
+
+	RegisterIndicator("Stochastic", func(params []float64) (Indicator, error) {
+
+		periods, err := periodParams(params, 14, 3, 3)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewStochastic(periods[0], periods[1], periods[2]), nil
+	})
+
+	for _, name := range []string{"StochasticRSI", "StochRSI"} {
+
+		RegisterIndicator(name, func(params []float64) (Indicator, error) {
+
+			periods, err := periodParams(params, 14, 14, 3, 3)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return NewStochasticRSI(periods[0], periods[1], periods[2], periods[3]), nil
+		})
+	}
+
+	RegisterIndicator("CCI", func(params []float64) (Indicator, error) {
+
+		period, err := periodParam(params, 0, 20)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewCCI(period), nil
+	})
+}
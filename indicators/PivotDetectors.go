@@ -0,0 +1,608 @@
+package indicators
+
+import (
+	"github.com/NERON/tran/candlescommon"
+	"math"
+)
+
+// PivotLowDetector finds lows from candles, low can be confirmed several candles after it
+type PivotLowDetector interface {
+	ReverseLowInterface
+	AddCandle(kline candlescommon.KLine)
+	ConfirmedLows() []int
+
+	//ConfirmationDelay is the most candles back low can be confirmed, negative is no limit
+	ConfirmationDelay() int
+
+	//PendingLows is offsets of candles that the next candles can still confirm as lows
+	PendingLows() []int
+
+	//WarmUpCandles is how many last candles detector needs to find lows of the next candles like after all candles,
+	//negative is no limit
+	WarmUpCandles() int
+}
+
+// defaultPivotDetector is the rule of GenerateMapLows: previous low is lower than its neighbours
+// or bullish candle makes lower low than previous candle. With High it's the rule of GenerateMapHighs
+type defaultPivotDetector struct {
+	Reverse   []float64
+	PrevLow   float64
+	HasPrev   bool
+	Confirmed []int
+	High      bool `json:",omitempty"`
+
+	pending []int
+}
+
+func (d *defaultPivotDetector) reverse() rsiReverse {
+	return rsiReverse{lastRSIValues: d.Reverse, high: d.High}
+}
+
+func (d *defaultPivotDetector) AddCandle(kline candlescommon.KLine) {
+
+	reverse := d.reverse()
+	reverse.AddPoint(pivotPrice(kline, d.High))
+
+	d.Confirmed = d.Confirmed[:0]
+
+	if reverse.isPreviousPivot() {
+		d.Confirmed = append(d.Confirmed, 1)
+	} else if d.HasPrev && isReversalCandle(d.PrevLow, kline, d.High) {
+		d.Confirmed = append(d.Confirmed, 0)
+	}
+
+	d.PrevLow = pivotPrice(kline, d.High)
+	d.HasPrev = true
+}
+
+// AddPoint uses value as all prices of candle
+func (d *defaultPivotDetector) AddPoint(value float64) {
+	d.AddCandle(candlescommon.KLine{OpenPrice: value, ClosePrice: value, HighPrice: value, LowPrice: value})
+}
+
+func (d *defaultPivotDetector) ConfirmedLows() []int {
+	return d.Confirmed
+}
+
+func (d *defaultPivotDetector) ConfirmationDelay() int {
+	return 1
+}
+
+// WarmUpCandles is two previous lows of 3-point reverse
+func (d *defaultPivotDetector) WarmUpCandles() int {
+	return 2
+}
+
+// PendingLows is the last candle if it isn't higher than previous one, the next candle can make it 3-point low
+func (d *defaultPivotDetector) PendingLows() []int {
+
+	d.pending = d.pending[:0]
+
+	previous, last := d.Reverse[1], d.Reverse[2]
+
+	if d.High {
+		previous, last = -previous, -last
+	}
+
+	if d.Reverse[1] >= 0 && last <= previous {
+		d.pending = append(d.pending, 0)
+	}
+
+	return d.pending
+}
+
+func (d *defaultPivotDetector) IsPreviousLow() bool {
+
+	reverse := d.reverse()
+
+	return reverse.isPreviousPivot()
+}
+
+// IsPreviousHigh is IsPreviousLow of detector of highs
+func (d *defaultPivotDetector) IsPreviousHigh() bool {
+	return d.IsPreviousLow()
+}
+
+func (d *defaultPivotDetector) Value() (float64, bool) {
+	return pivotValue(d.HasPrev, d.Confirmed)
+}
+
+func (d *defaultPivotDetector) clone() *defaultPivotDetector {
+
+	clone := *d
+	clone.Reverse = append([]float64(nil), d.Reverse...)
+	clone.Confirmed = append([]int(nil), d.Confirmed...)
+	clone.pending = nil
+
+	return &clone
+}
+
+func (d *defaultPivotDetector) Clone() Indicator {
+	return d.clone()
+}
+
+func (d *defaultPivotDetector) Snapshot() IndicatorSnapshot {
+	return d.clone()
+}
+
+func (d *defaultPivotDetector) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*defaultPivotDetector)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*d = *state.clone()
+
+	return nil
+}
+
+func (d *defaultPivotDetector) name() string {
+
+	if d.High {
+		return "DefaultHighs"
+	}
+
+	return "DefaultLows"
+}
+
+func (d *defaultPivotDetector) Marshal() ([]byte, error) {
+	return marshalState(d.name(), 1, d)
+}
+
+func (d *defaultPivotDetector) Unmarshal(data []byte) error {
+	return unmarshalState(data, d.name(), 1, d)
+}
+
+func NewDefaultLowDetector() PivotLowDetector {
+	return &defaultPivotDetector{Reverse: []float64{-1, -1, -1}}
+}
+
+// NewDefaultHighDetector finds highs by the default rule, confirmed offsets are offsets of highs
+func NewDefaultHighDetector() PivotLowDetector {
+	return &defaultPivotDetector{Reverse: []float64{-1, -1, -1}, High: true}
+}
+
+// fractalLowDetector confirms low when it's not higher than Left lows before and lower than Right lows after
+type fractalLowDetector struct {
+	Left      int
+	Right     int
+	Lows      []float64
+	Confirmed []int
+
+	pending []int
+}
+
+func (f *fractalLowDetector) AddCandle(kline candlescommon.KLine) {
+
+	f.Lows = append(f.Lows, kline.LowPrice)
+
+	if len(f.Lows) > f.Left+f.Right+1 {
+		f.Lows = f.Lows[1:]
+	}
+
+	f.Confirmed = f.Confirmed[:0]
+
+	if len(f.Lows) < f.Left+f.Right+1 {
+		return
+	}
+
+	candidate := f.Lows[f.Left]
+
+	for i := 0; i < f.Left; i++ {
+
+		if f.Lows[i] < candidate {
+			return
+		}
+	}
+
+	for i := f.Left + 1; i < len(f.Lows); i++ {
+
+		if f.Lows[i] <= candidate {
+			return
+		}
+	}
+
+	f.Confirmed = append(f.Confirmed, f.Right)
+}
+
+// AddPoint uses value as all prices of candle
+func (f *fractalLowDetector) AddPoint(value float64) {
+	f.AddCandle(candlescommon.KLine{OpenPrice: value, ClosePrice: value, HighPrice: value, LowPrice: value})
+}
+
+func (f *fractalLowDetector) ConfirmedLows() []int {
+	return f.Confirmed
+}
+
+func (f *fractalLowDetector) ConfirmationDelay() int {
+	return f.Right
+}
+
+func (f *fractalLowDetector) WarmUpCandles() int {
+	return f.Left + f.Right + 1
+}
+
+// PendingLows is candles less than Right candles back that have Left lows before them
+// and are lower than all lows after them
+func (f *fractalLowDetector) PendingLows() []int {
+
+	f.pending = f.pending[:0]
+
+	for offset := 0; offset < f.Right && offset < len(f.Lows); offset++ {
+
+		position := len(f.Lows) - 1 - offset
+
+		if position < f.Left {
+			break
+		}
+
+		if f.isCandidate(position) {
+			f.pending = append(f.pending, offset)
+		}
+	}
+
+	return f.pending
+}
+
+func (f *fractalLowDetector) isCandidate(position int) bool {
+
+	candidate := f.Lows[position]
+
+	for i := position - f.Left; i < position; i++ {
+
+		if f.Lows[i] < candidate {
+			return false
+		}
+	}
+
+	for i := position + 1; i < len(f.Lows); i++ {
+
+		if f.Lows[i] <= candidate {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsPreviousLow returns true if the last candle confirmed low, low is Right candles back
+func (f *fractalLowDetector) IsPreviousLow() bool {
+	return len(f.Confirmed) > 0
+}
+
+func (f *fractalLowDetector) Value() (float64, bool) {
+	return pivotValue(len(f.Lows) == f.Left+f.Right+1, f.Confirmed)
+}
+
+func (f *fractalLowDetector) clone() *fractalLowDetector {
+
+	clone := *f
+	clone.Lows = append(make([]float64, 0, f.Left+f.Right+1), f.Lows...)
+	clone.Confirmed = append([]int(nil), f.Confirmed...)
+	clone.pending = nil
+
+	return &clone
+}
+
+func (f *fractalLowDetector) Clone() Indicator {
+	return f.clone()
+}
+
+func (f *fractalLowDetector) Snapshot() IndicatorSnapshot {
+	return f.clone()
+}
+
+func (f *fractalLowDetector) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*fractalLowDetector)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*f = *state.clone()
+
+	return nil
+}
+
+func (f *fractalLowDetector) Marshal() ([]byte, error) {
+	return marshalState("Fractal", 1, f)
+}
+
+func (f *fractalLowDetector) Unmarshal(data []byte) error {
+	return unmarshalState(data, "Fractal", 1, f)
+}
+
+func NewFractalLowDetector(left int, right int) PivotLowDetector {
+
+	if left < 1 {
+		left = 1
+	}
+
+	if right < 1 {
+		right = 1
+	}
+
+	return &fractalLowDetector{Left: left, Right: right}
+}
+
+// zigZagLowDetector confirms the lowest low of falling leg when price rises from it by Percent
+// or by Multiplier ATR if ATR is set
+type zigZagLowDetector struct {
+	Percent    float64
+	Multiplier float64
+	ATR        *ATR `json:",omitempty"`
+
+	Rising       bool
+	Extreme      float64
+	ExtremeIndex int
+	Index        int
+	Started      bool
+	Confirmed    []int
+
+	pending []int
+}
+
+func (z *zigZagLowDetector) reversal(from float64) (float64, bool) {
+
+	if z.ATR == nil {
+		return from * z.Percent / 100, true
+	}
+
+	atr, ok := z.ATR.Calculate()
+
+	return atr * z.Multiplier, ok
+}
+
+func (z *zigZagLowDetector) AddCandle(kline candlescommon.KLine) {
+
+	z.Confirmed = z.Confirmed[:0]
+
+	reversal, ready := z.reversal(z.Extreme)
+
+	if z.ATR != nil {
+		z.ATR.AddCandle(kline)
+	}
+
+	if !z.Started {
+
+		z.Extreme = kline.LowPrice
+		z.ExtremeIndex = z.Index
+		z.Started = true
+
+	} else if !z.Rising {
+
+		if kline.LowPrice <= z.Extreme {
+
+			z.Extreme = kline.LowPrice
+			z.ExtremeIndex = z.Index
+
+		} else if ready && kline.HighPrice-z.Extreme >= reversal {
+
+			z.Confirmed = append(z.Confirmed, z.Index-z.ExtremeIndex)
+
+			z.Rising = true
+			z.Extreme = kline.HighPrice
+			z.ExtremeIndex = z.Index
+		}
+
+	} else {
+
+		if kline.HighPrice >= z.Extreme {
+
+			z.Extreme = kline.HighPrice
+			z.ExtremeIndex = z.Index
+
+		} else if ready && z.Extreme-kline.LowPrice >= reversal {
+
+			z.Rising = false
+			z.Extreme = kline.LowPrice
+			z.ExtremeIndex = z.Index
+		}
+	}
+
+	z.Index++
+}
+
+// AddPoint uses value as all prices of candle
+func (z *zigZagLowDetector) AddPoint(value float64) {
+	z.AddCandle(candlescommon.KLine{OpenPrice: value, ClosePrice: value, HighPrice: value, LowPrice: value})
+}
+
+func (z *zigZagLowDetector) ConfirmedLows() []int {
+	return z.Confirmed
+}
+
+func (z *zigZagLowDetector) ConfirmationDelay() int {
+	return -1
+}
+
+// WarmUpCandles isn't limited, the current leg can start at any candle
+func (z *zigZagLowDetector) WarmUpCandles() int {
+	return -1
+}
+
+// PendingLows is the lowest low of falling leg, it's confirmed when price rises from it
+func (z *zigZagLowDetector) PendingLows() []int {
+
+	z.pending = z.pending[:0]
+
+	if z.Started && !z.Rising {
+		z.pending = append(z.pending, z.Index-1-z.ExtremeIndex)
+	}
+
+	return z.pending
+}
+
+// IsPreviousLow returns true if the last candle confirmed low of falling leg
+func (z *zigZagLowDetector) IsPreviousLow() bool {
+	return len(z.Confirmed) > 0
+}
+
+func (z *zigZagLowDetector) Value() (float64, bool) {
+	return pivotValue(z.Started, z.Confirmed)
+}
+
+func (z *zigZagLowDetector) clone() *zigZagLowDetector {
+
+	clone := *z
+	clone.Confirmed = append([]int(nil), z.Confirmed...)
+	clone.pending = nil
+
+	if z.ATR != nil {
+		clone.ATR = z.ATR.clone()
+	}
+
+	return &clone
+}
+
+func (z *zigZagLowDetector) Clone() Indicator {
+	return z.clone()
+}
+
+func (z *zigZagLowDetector) Snapshot() IndicatorSnapshot {
+	return z.clone()
+}
+
+func (z *zigZagLowDetector) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*zigZagLowDetector)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*z = *state.clone()
+
+	return nil
+}
+
+func (z *zigZagLowDetector) Marshal() ([]byte, error) {
+	return marshalState("ZigZag", 1, z)
+}
+
+func (z *zigZagLowDetector) Unmarshal(data []byte) error {
+	return unmarshalState(data, "ZigZag", 1, z)
+}
+
+func NewZigZagLowDetector(percent float64) PivotLowDetector {
+	return &zigZagLowDetector{Percent: math.Abs(percent)}
+}
+
+func NewZigZagATRLowDetector(atrPeriod uint, multiplier float64) PivotLowDetector {
+	return &zigZagLowDetector{Multiplier: math.Abs(multiplier), ATR: NewATR(atrPeriod)}
+}
+
+// pivotValue returns 1 if the last candle confirmed low
+func pivotValue(ready bool, confirmed []int) (float64, bool) {
+
+	if !ready {
+		return 0, false
+	}
+
+	if len(confirmed) > 0 {
+		return 1, true
+	}
+
+	return 0, true
+}
+
+// NewLowDetectorFromString creates low detector from description like fractal:2:2, zigzag:5 or zigzagatr:14:3,
+// empty description is the default rule
+func NewLowDetectorFromString(description string) (ReverseLowInterface, error) {
+
+	if description == "" {
+		return NewDefaultLowDetector(), nil
+	}
+
+	indicator, err := NewIndicatorFromString(description)
+
+	if err != nil {
+		return nil, err
+	}
+
+	detector, ok := indicator.(ReverseLowInterface)
+
+	if !ok {
+		return nil, errNotLowDetector
+	}
+
+	return detector, nil
+}
+
+// PrimeLowDetector passes old candles to detector before candles that are searched for lows.
+// Candle detectors get all old candles, the default 3-point detector only the last low.
+// The default candle detector doesn't compare the first candle with the last old candle, like GenerateMapLows
+func PrimeLowDetector(lowReverse ReverseLowInterface, candlesOld []candlescommon.KLine) {
+
+	if detector, ok := lowReverse.(PivotLowDetector); ok {
+
+		for _, candle := range candlesOld {
+			detector.AddCandle(candle)
+		}
+
+		if defaultDetector, ok := detector.(*defaultPivotDetector); ok {
+			defaultDetector.HasPrev = false
+		}
+
+		return
+	}
+
+	if len(candlesOld) > 0 {
+		lowReverse.AddPoint(candlesOld[len(candlesOld)-1].LowPrice)
+	}
+}
+
+func init() {
+
+	RegisterIndicator("DefaultLows", func(params []float64) (Indicator, error) {
+		return NewDefaultLowDetector(), nil
+	})
+
+	RegisterIndicator("Fractal", func(params []float64) (Indicator, error) {
+
+		left, err := periodParam(params, 0, 2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := periodParam(params, 1, left)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewFractalLowDetector(int(left), int(right)), nil
+	})
+
+	RegisterIndicator("ZigZag", func(params []float64) (Indicator, error) {
+
+		percent, err := positiveParam(params, 0, 5)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewZigZagLowDetector(percent), nil
+	})
+
+	RegisterIndicator("ZigZagATR", func(params []float64) (Indicator, error) {
+
+		period, err := periodParam(params, 0, 14)
+
+		if err != nil {
+			return nil, err
+		}
+
+		multiplier, err := positiveParam(params, 1, 3)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewZigZagATRLowDetector(period, multiplier), nil
+	})
+}
@@ -0,0 +1,125 @@
+package indicators
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/NERON/tran/candlescommon"
+)
+
+// baseline primes 3-point reverse only with the last old candle, so bullish rule can't flag the first candle
+func TestPrimedDefaultDetectorMatchesBaseline(t *testing.T) {
+
+	for seed := int64(1); seed <= 20; seed++ {
+
+		klines := randomKLines(seed, 300)
+		candlesOld, candles := klines[:100], klines[100:]
+
+		lowReverse := NewRSILowReverseIndicator()
+		lowReverse.AddPoint(candlesOld[len(candlesOld)-1].LowPrice)
+
+		lowDetector := NewDefaultLowDetector()
+		PrimeLowDetector(lowDetector, candlesOld)
+
+		if expected, found := GenerateMapLows(lowReverse, candles), GenerateMapLows(lowDetector, candles); !reflect.DeepEqual(expected, found) {
+			t.Fatalf("seed %d: lows %v, baseline %v", seed, found, expected)
+		}
+
+		highReverse := NewRSIHighReverseIndicator()
+		highReverse.AddPoint(candlesOld[len(candlesOld)-1].HighPrice)
+
+		highDetector := NewDefaultHighDetector()
+		PrimeLowDetector(highDetector, candlesOld)
+
+		if expected, found := GenerateMapHighs(highReverse, candles), GenerateMapLows(highDetector, candles); !reflect.DeepEqual(expected, found) {
+			t.Fatalf("seed %d: highs %v, baseline %v", seed, found, expected)
+		}
+	}
+}
+
+func TestLowDetectorFindsNoHighs(t *testing.T) {
+
+	candles := randomKLines(1, 300)
+
+	if highs := GenerateMapHighs(NewDefaultLowDetector().(ReverseHighInterface), candles); len(highs) != 0 {
+		t.Fatalf("detector of lows found highs %v", highs)
+	}
+
+	if highs := GenerateMapHighs(NewDefaultHighDetector().(ReverseHighInterface), candles); len(highs) == 0 {
+		t.Fatalf("detector of highs found no highs")
+	}
+}
+
+func TestPrimedDefaultDetectorSkipsFirstBullishCandle(t *testing.T) {
+
+	candlesOld := []candlescommon.KLine{
+		{OpenPrice: 12, ClosePrice: 11, HighPrice: 12, LowPrice: 10},
+		{OpenPrice: 11, ClosePrice: 10, HighPrice: 11, LowPrice: 9},
+	}
+
+	//bullish candle with lower low than the last old candle
+	first := candlescommon.KLine{OpenPrice: 9, ClosePrice: 10, HighPrice: 10, LowPrice: 8}
+
+	detector := NewDefaultLowDetector()
+	PrimeLowDetector(detector, candlesOld)
+
+	detector.AddCandle(first)
+
+	if len(detector.ConfirmedLows()) != 0 {
+		t.Fatalf("first candle confirmed lows %v", detector.ConfirmedLows())
+	}
+
+	//the same rule after the first candle finds low
+	detector.AddCandle(candlescommon.KLine{OpenPrice: 8, ClosePrice: 9, HighPrice: 9, LowPrice: 7})
+
+	if !reflect.DeepEqual(detector.ConfirmedLows(), []int{0}) {
+		t.Fatalf("second candle confirmed lows %v", detector.ConfirmedLows())
+	}
+}
+
+// detector that got only the last WarmUpCandles candles confirms the same lows as detector that got all candles
+func TestDetectorWarmUpCandles(t *testing.T) {
+
+	for _, description := range []string{"", "fractal:2:2", "fractal:5:3"} {
+
+		for seed := int64(1); seed <= 5; seed++ {
+
+			klines := randomKLines(seed, 400)
+
+			newDetector := func() PivotLowDetector {
+
+				detector, err := NewLowDetectorFromString(description)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return detector.(PivotLowDetector)
+			}
+
+			full := newDetector()
+
+			for _, candle := range klines[:300] {
+				full.AddCandle(candle)
+			}
+
+			primed := newDetector()
+
+			for _, candle := range klines[300-primed.WarmUpCandles() : 300] {
+				primed.AddCandle(candle)
+			}
+
+			for idx, candle := range klines[300:] {
+
+				full.AddCandle(candle)
+				primed.AddCandle(candle)
+
+				expected, found := full.ConfirmedLows(), primed.ConfirmedLows()
+
+				if (len(expected) > 0 || len(found) > 0) && !reflect.DeepEqual(expected, found) {
+					t.Fatalf("%q seed %d candle %d: lows %v, expected %v", description, seed, idx, found, expected)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package indicators
+
+import (
+	"fmt"
+	"github.com/NERON/tran/candlescommon"
+)
+
+const (
+	PriceSourceClose = "close"
+	PriceSourceLow   = "low"
+	PriceSourceHL2   = "hl2"
+	PriceSourceHLC3  = "hlc3"
+	PriceSourceOHLC4 = "ohlc4"
+)
+
+// SourcePrice returns candle price used by indicator, close price is default
+func SourcePrice(kline candlescommon.KLine, source string) float64 {
+
+	switch source {
+
+	case PriceSourceLow:
+		return kline.LowPrice
+
+	case PriceSourceHL2:
+		return (kline.HighPrice + kline.LowPrice) / 2
+
+	case PriceSourceHLC3:
+		return (kline.HighPrice + kline.LowPrice + kline.ClosePrice) / 3
+
+	case PriceSourceOHLC4:
+		return (kline.OpenPrice + kline.HighPrice + kline.LowPrice + kline.ClosePrice) / 4
+	}
+
+	return kline.ClosePrice
+}
+
+func checkPriceSource(source string) error {
+
+	switch source {
+
+	case "", PriceSourceClose, PriceSourceLow, PriceSourceHL2, PriceSourceHLC3, PriceSourceOHLC4:
+		return nil
+	}
+
+	return fmt.Errorf("unknown price source %s", source)
+}
@@ -1,25 +1,96 @@
 package indicators
 
-import "math"
+import (
+	"fmt"
+	"github.com/NERON/tran/candlescommon"
+	"math"
+)
+
+const (
+	RSISmoothingWilder = "wilder"
+	RSISmoothingEMA    = "ema"
+	RSISmoothingSMA    = "sma"
+)
+
+type PredictionStatus int
+
+const (
+	PredictionOK PredictionStatus = iota
+	PredictionNotReady
+	PredictionUnreachable
+	PredictionDegenerate
+)
+
+func (status PredictionStatus) String() string {
+
+	switch status {
+
+	case PredictionOK:
+		return "ok"
+
+	case PredictionNotReady:
+		return "not enough points"
+
+	case PredictionUnreachable:
+		return "RSI value is unreachable with one point"
+
+	case PredictionDegenerate:
+		return "predicted price isn't positive"
+	}
+
+	return "unknown"
+}
 
 type RSI struct {
 	Period uint
 
+	//empty smoothing is Wilder and empty source is close price
+	Smoothing string `json:",omitempty"`
+	Source    string `json:",omitempty"`
+
 	AvgGain float64
 	AvgLoss float64
 
+	//last gains and losses for Cutler's RSI
+	Gains  []float64 `json:",omitempty"`
+	Losses []float64 `json:",omitempty"`
+
 	PointsCount uint
 	LastValue   float64
 }
 
+// retainFactor is weight of previous average relative to new point for exponential smoothing
+func (rsi *RSI) retainFactor() float64 {
+
+	if rsi.Smoothing == RSISmoothingEMA {
+		return float64(rsi.Period-1) / 2
+	}
+
+	return float64(rsi.Period - 1)
+}
+
 func (rsi *RSI) AddPoint(value float64) {
 
 	rsi.PointsCount++
 
+	gain := math.Max(value-rsi.LastValue, 0)
+	loss := math.Max(rsi.LastValue-value, 0)
+
+	if rsi.PointsCount > 1 && rsi.Smoothing == RSISmoothingSMA {
+
+		rsi.Gains = append(rsi.Gains, gain)
+		rsi.Losses = append(rsi.Losses, loss)
+
+		if len(rsi.Gains) > int(rsi.Period) {
+			rsi.Gains = rsi.Gains[1:]
+			rsi.Losses = rsi.Losses[1:]
+		}
+	}
+
 	if rsi.PointsCount > 1 && rsi.PointsCount <= rsi.Period+1 {
 
-		rsi.AvgGain += math.Max(value-rsi.LastValue, 0)
-		rsi.AvgLoss += math.Max(rsi.LastValue-value, 0)
+		rsi.AvgGain += gain
+		rsi.AvgLoss += loss
 
 		if rsi.PointsCount == rsi.Period+1 {
 
@@ -29,58 +100,228 @@ func (rsi *RSI) AddPoint(value float64) {
 
 	} else if rsi.PointsCount > rsi.Period+1 {
 
-		rsi.AvgGain = (float64(rsi.Period-1)*rsi.AvgGain + math.Max(value-rsi.LastValue, 0)) / float64(rsi.Period)
-		rsi.AvgLoss = (float64(rsi.Period-1)*rsi.AvgLoss + math.Max(rsi.LastValue-value, 0)) / float64(rsi.Period)
+		if rsi.Smoothing == RSISmoothingSMA {
+
+			//window sums are recalculated to avoid accumulation of rounding errors
+			rsi.AvgGain = 0
+			rsi.AvgLoss = 0
+
+			for i := 0; i < len(rsi.Gains); i++ {
+				rsi.AvgGain += rsi.Gains[i]
+				rsi.AvgLoss += rsi.Losses[i]
+			}
+
+			rsi.AvgGain /= float64(rsi.Period)
+			rsi.AvgLoss /= float64(rsi.Period)
+
+		} else {
+
+			retain := rsi.retainFactor()
+
+			rsi.AvgGain = (retain*rsi.AvgGain + gain) / (retain + 1)
+			rsi.AvgLoss = (retain*rsi.AvgLoss + loss) / (retain + 1)
+		}
 	}
 
 	rsi.LastValue = value
 
 }
 
+// AddCandle adds candle price selected by Source
+func (rsi *RSI) AddCandle(kline candlescommon.KLine) {
+	rsi.AddPoint(SourcePrice(kline, rsi.Source))
+}
+
 func (rsi *RSI) Calculate() (float64, bool) {
 
 	if rsi.PointsCount < rsi.Period+1 {
 		return 0, false
 	}
 
-	return 100 - 100/(1+rsi.AvgGain/rsi.AvgLoss), true
+	return rsiFromGainLoss(rsi.AvgGain, rsi.AvgLoss), true
+}
+
+// rsiFromGainLoss defines edge cases: flat market is 50, only gains is 100 and only losses is 0
+func rsiFromGainLoss(gain float64, loss float64) float64 {
+
+	if loss == 0 {
+
+		if gain == 0 {
+			return 50
+		}
+
+		return 100
+	}
+
+	return 100 - 100/(1+gain/loss)
+}
+func (rsi *RSI) Value() (float64, bool) {
+	return rsi.Calculate()
+}
+
+func (rsi *RSI) clone() RSI {
+
+	clone := *rsi
+
+	if rsi.Gains != nil {
+
+		clone.Gains = make([]float64, len(rsi.Gains), rsi.Period)
+		clone.Losses = make([]float64, len(rsi.Losses), rsi.Period)
+
+		copy(clone.Gains, rsi.Gains)
+		copy(clone.Losses, rsi.Losses)
+	}
+
+	return clone
+}
+
+func (rsi *RSI) Clone() Indicator {
+
+	clone := rsi.clone()
+
+	return &clone
 }
+
+func (rsi *RSI) Snapshot() IndicatorSnapshot {
+	return rsi.clone()
+}
+
+func (rsi *RSI) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(RSI)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*rsi = state.clone()
+
+	return nil
+}
+
+func (rsi *RSI) Marshal() ([]byte, error) {
+	return marshalState("RSI", 1, rsi)
+}
+
+func (rsi *RSI) Unmarshal(data []byte) error {
+	return unmarshalState(data, "RSI", 1, rsi)
+}
+
 func (rsi *RSI) PredictForNextPoint(value float64) (float64, bool) {
 
-	avgG := rsi.AvgGain
-	avgL := rsi.AvgLoss
-	pC := rsi.PointsCount
-	lV := rsi.LastValue
+	snapshot := rsi.Snapshot()
 
 	rsi.AddPoint(value)
 
 	result, notNaN := rsi.Calculate()
 
-	rsi.AvgGain = avgG
-	rsi.AvgLoss = avgL
-	rsi.PointsCount = pC
-	rsi.LastValue = lV
+	rsi.Restore(snapshot)
 
 	return result, notNaN
 
 }
+
+// retainedGainLoss returns gain and loss sums that stay in average after next point,
+// next average is (retained + new) divided by the same number for gains and losses
+func (rsi *RSI) retainedGainLoss() (float64, float64) {
+
+	if rsi.Smoothing == RSISmoothingSMA {
+
+		gain := rsi.AvgGain * float64(rsi.Period)
+		loss := rsi.AvgLoss * float64(rsi.Period)
+
+		//oldest point leaves the window
+		if len(rsi.Gains) == int(rsi.Period) {
+			gain -= rsi.Gains[0]
+			loss -= rsi.Losses[0]
+		}
+
+		return gain, loss
+	}
+
+	retain := rsi.retainFactor()
+
+	return retain * rsi.AvgGain, retain * rsi.AvgLoss
+}
+
 func (rsi *RSI) PredictPrice(RSIValue float64) (float64, bool) {
 
-	currentRSI, ok := rsi.Calculate()
+	price, status := rsi.PredictPriceStatus(RSIValue)
 
-	if !ok {
-		return 0, false
+	return price, status == PredictionOK
+}
+
+// PredictPriceStatus returns price of next point that gives RSIValue,
+// status explains why price can't be predicted
+func (rsi *RSI) PredictPriceStatus(RSIValue float64) (float64, PredictionStatus) {
+
+	if rsi.PointsCount < rsi.Period+1 {
+		return 0, PredictionNotReady
+	}
+
+	retainedGain, retainedLoss := rsi.retainedGainLoss()
+
+	return predictPriceFromRetained(retainedGain, retainedLoss, rsi.LastValue, RSIValue)
+}
+
+// predictPriceFromRetained solves RSI equation for next point when retained gain and loss sums are known,
+// price that isn't positive finite number is degenerate
+func predictPriceFromRetained(retainedGain float64, retainedLoss float64, lastValue float64, RSIValue float64) (float64, PredictionStatus) {
+
+	price, status := solvePriceFromRetained(retainedGain, retainedLoss, lastValue, RSIValue)
+
+	//NaN doesn't pass comparison
+	if status == PredictionOK && (!(price > 0) || math.IsInf(price, 1)) {
+		return 0, PredictionDegenerate
+	}
+
+	return price, status
+}
+
+func solvePriceFromRetained(retainedGain float64, retainedLoss float64, lastValue float64, RSIValue float64) (float64, PredictionStatus) {
+
+	//RSI if next point is equal to the last one
+	unchangedRSI := rsiFromGainLoss(retainedGain, retainedLoss)
+
+	if RSIValue == unchangedRSI {
+		return lastValue, PredictionOK
+	}
+
+	//extreme values are reached only at infinite distance or without opposite moves
+	if RSIValue <= 0 || RSIValue >= 100 {
+		return 0, PredictionUnreachable
 	}
 
 	coef := RSIValue / (100 - RSIValue)
 
-	if currentRSI >= RSIValue {
+	if RSIValue < unchangedRSI {
+
+		//price falls, without retained gains any fall gives RSI 0
+		if retainedGain == 0 {
+			return 0, PredictionUnreachable
+		}
+
+		return retainedLoss - retainedGain/coef + lastValue, PredictionOK
+	}
+
+	//price rises, without retained losses any rise gives RSI 100
+	if retainedLoss == 0 {
+		return 0, PredictionUnreachable
+	}
+
+	return retainedLoss*coef - retainedGain + lastValue, PredictionOK
+}
+
+// CheckRSISettings returns error if smoothing or price source is unknown
+func CheckRSISettings(smoothing string, source string) error {
 
-		return float64(rsi.Period-1)*(rsi.AvgLoss-rsi.AvgGain/coef)  + rsi.LastValue, true
+	switch smoothing {
 
-	} else {
+	case "", RSISmoothingWilder, RSISmoothingEMA, RSISmoothingSMA:
 
-		return float64(rsi.Period-1)*(rsi.AvgLoss*coef-rsi.AvgGain)  + rsi.LastValue, true
+	default:
+		return fmt.Errorf("unknown RSI smoothing %s", smoothing)
 	}
 
+	return checkPriceSource(source)
 }
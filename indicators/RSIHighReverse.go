@@ -0,0 +1,48 @@
+package indicators
+
+import (
+	"github.com/NERON/tran/candlescommon"
+)
+
+type ReverseHighInterface interface {
+	Indicator
+	IsPreviousHigh() bool
+}
+
+type rsiHighReverse struct {
+	rsiReverse
+}
+
+func (r *rsiHighReverse) Clone() Indicator {
+	return &rsiHighReverse{rsiReverse: r.clone()}
+}
+
+func (r *rsiHighReverse) IsPreviousHigh() bool {
+	return r.isPreviousPivot()
+}
+
+func NewRSIHighReverseIndicator() ReverseHighInterface {
+
+	lastValues := []float64{-1, -1, -1}
+
+	return &rsiHighReverse{rsiReverse: rsiReverse{lastRSIValues: lastValues, high: true}}
+
+}
+
+// GenerateMapHighs is mirror of GenerateMapLows, it uses high prices and bearish candles.
+// Only the default rule has detector of highs, detector of lows finds no highs
+func GenerateMapHighs(highReverse ReverseHighInterface, candles []candlescommon.KLine) map[int]struct{} {
+
+	//candle detector of highs reports offsets like low detectors
+	if detector, ok := highReverse.(*defaultPivotDetector); ok {
+
+		if !detector.High {
+			return make(map[int]struct{})
+		}
+
+		return GenerateMapLows(detector, candles)
+	}
+
+	return generateMapPivots(highReverse, highReverse.IsPreviousHigh, candles, true)
+
+}
@@ -0,0 +1,237 @@
+package indicators
+
+import (
+	"math"
+	"sort"
+)
+
+// ladderSegment is indexes from first to last where both zone borders move in one direction
+type ladderSegment struct {
+	first  int
+	last   int
+	rising bool
+}
+
+// rsiLadder keeps zones of all periods for one state of RSIMultiplePeriods and central RSI.
+// Border closer to shorter periods is upper border for lows and lower border for highs
+type rsiLadder struct {
+	valid      bool
+	centralRSI float64
+
+	central       []float64
+	neighbour     []float64
+	shorterBorder []float64
+	longerBorder  []float64
+	ok            []bool
+	hasNeighbour  []bool
+
+	segments []ladderSegment
+
+	//range of borders among zones that exist
+	shorterMin, shorterMax float64
+	longerMin, longerMax   float64
+}
+
+// zonesLadder returns zones for centralRSI, they are calculated again only after state changes
+func (rsip *RSIMultiplePeriods) zonesLadder(centralRSI float64) *rsiLadder {
+
+	ladder := &rsip.ladder
+
+	if ladder.valid && ladder.centralRSI == centralRSI {
+		return ladder
+	}
+
+	count := len(rsip.Periods)
+
+	if cap(ladder.central) < count {
+
+		ladder.central = make([]float64, count)
+		ladder.neighbour = make([]float64, count)
+		ladder.shorterBorder = make([]float64, count)
+		ladder.longerBorder = make([]float64, count)
+		ladder.ok = make([]bool, count)
+		ladder.hasNeighbour = make([]bool, count)
+	}
+
+	ladder.central = ladder.central[:count]
+	ladder.neighbour = ladder.neighbour[:count]
+	ladder.shorterBorder = ladder.shorterBorder[:count]
+	ladder.longerBorder = ladder.longerBorder[:count]
+	ladder.ok = ladder.ok[:count]
+	ladder.hasNeighbour = ladder.hasNeighbour[:count]
+
+	for idx := 0; idx < count; idx++ {
+
+		price, status := rsip.predictPrice(idx, centralRSI)
+
+		ladder.central[idx] = price
+		ladder.ok[idx] = status == PredictionOK
+		ladder.neighbour[idx], ladder.hasNeighbour[idx] = rsip.neighbourFromPrediction(price, status)
+	}
+
+	ladder.shorterMin, ladder.longerMin = math.Inf(1), math.Inf(1)
+	ladder.shorterMax, ladder.longerMax = math.Inf(-1), math.Inf(-1)
+
+	for idx := 0; idx < count; idx++ {
+
+		central := ladder.central[idx]
+
+		ladder.shorterBorder[idx], ladder.longerBorder[idx] = central, central
+
+		if !ladder.ok[idx] {
+			continue
+		}
+
+		if idx > 0 && ladder.hasNeighbour[idx-1] {
+			ladder.shorterBorder[idx] = (central + ladder.neighbour[idx-1]) / 2
+		}
+
+		if idx < count-1 && ladder.hasNeighbour[idx+1] {
+			ladder.longerBorder[idx] = (central + ladder.neighbour[idx+1]) / 2
+		}
+
+		//the first and the last periods only bound neighbour zones
+		if idx > 0 && idx < count-1 {
+
+			ladder.shorterMin = math.Min(ladder.shorterMin, ladder.shorterBorder[idx])
+			ladder.shorterMax = math.Max(ladder.shorterMax, ladder.shorterBorder[idx])
+			ladder.longerMin = math.Min(ladder.longerMin, ladder.longerBorder[idx])
+			ladder.longerMax = math.Max(ladder.longerMax, ladder.longerBorder[idx])
+		}
+	}
+
+	ladder.buildSegments()
+
+	ladder.centralRSI = centralRSI
+	ladder.valid = true
+
+	return ladder
+}
+
+// direction returns 1 if both borders of zone idx aren't lower than borders of previous zone,
+// -1 if both aren't higher, 0 if they are equal and 2 if borders move in different directions
+func (ladder *rsiLadder) direction(idx int) int {
+
+	shorter := ladder.shorterBorder[idx] - ladder.shorterBorder[idx-1]
+	longer := ladder.longerBorder[idx] - ladder.longerBorder[idx-1]
+
+	switch {
+
+	case shorter == 0 && longer == 0:
+		return 0
+
+	case shorter >= 0 && longer >= 0:
+		return 1
+
+	case shorter <= 0 && longer <= 0:
+		return -1
+	}
+
+	return 2
+}
+
+// buildSegments splits zones of periods except the first and the last to segments where borders move in one direction,
+// zones that can't be calculated don't belong to any segment
+func (ladder *rsiLadder) buildSegments() {
+
+	ladder.segments = ladder.segments[:0]
+
+	last := len(ladder.central) - 2
+
+	for idx := 1; idx <= last; {
+
+		if !ladder.ok[idx] {
+			idx++
+			continue
+		}
+
+		segment := ladderSegment{first: idx, last: idx}
+		direction := 0
+
+		for next := idx + 1; next <= last && ladder.ok[next]; next++ {
+
+			nextDirection := ladder.direction(next)
+
+			if nextDirection == 2 || (direction != 0 && nextDirection != 0 && nextDirection != direction) {
+				break
+			}
+
+			if direction == 0 {
+				direction = nextDirection
+			}
+
+			segment.last = next
+		}
+
+		segment.rising = direction > 0
+
+		ladder.segments = append(ladder.segments, segment)
+
+		idx = segment.last + 1
+	}
+}
+
+// borders returns upper and lower borders of zone
+func (ladder *rsiLadder) borders(idx int, high bool) (float64, float64) {
+
+	if high {
+		return ladder.longerBorder[idx], ladder.shorterBorder[idx]
+	}
+
+	return ladder.shorterBorder[idx], ladder.longerBorder[idx]
+}
+
+// find returns index of the longest period which zone contains price
+func (ladder *rsiLadder) find(priceFor float64, high bool) (int, bool) {
+
+	for s := len(ladder.segments) - 1; s >= 0; s-- {
+
+		segment := ladder.segments[s]
+		count := segment.last - segment.first + 1
+
+		//zones that passed one border form the beginning of segment, the last of them is the only candidate
+		idx := segment.first + sort.Search(count, func(i int) bool {
+
+			up, down := ladder.borders(segment.first+i, high)
+
+			if segment.rising {
+				return down > priceFor
+			}
+
+			return up < priceFor
+
+		}) - 1
+
+		if idx < segment.first {
+			continue
+		}
+
+		up, down := ladder.borders(idx, high)
+
+		if priceFor <= up && priceFor >= down {
+			return idx, true
+		}
+	}
+
+	return 0, false
+}
+
+// position returns position of price that isn't inside any zone
+func (ladder *rsiLadder) position(priceFor float64, high bool) LadderPosition {
+
+	highest, lowest := ladder.shorterMax, ladder.longerMin
+
+	if high {
+		highest, lowest = ladder.longerMax, ladder.shorterMin
+	}
+
+	if priceFor > highest && !math.IsInf(highest, -1) {
+		return LadderAbove
+	}
+
+	if priceFor < lowest && !math.IsInf(lowest, 1) {
+		return LadderBelow
+	}
+
+	return LadderUndefined
+}
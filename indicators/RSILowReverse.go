@@ -5,15 +5,18 @@ import (
 )
 
 type ReverseLowInterface interface {
-	AddPoint(calcValue float64, addValue float64)
+	Indicator
 	IsPreviousLow() bool
 }
 
-type rsiLowReverse struct {
+// rsiReverse keeps the last three points, previous point is low if it's lower than neighbours.
+// Highs are found by the same rule on negated points, so comparison is mirrored
+type rsiReverse struct {
 	lastRSIValues []float64
+	high          bool
 }
 
-func (r *rsiLowReverse) AddPoint(calcValue float64, addValue float64) {
+func (r *rsiReverse) AddPoint(calcValue float64) {
 
 	r.lastRSIValues[0] = r.lastRSIValues[1]
 	r.lastRSIValues[1] = r.lastRSIValues[2]
@@ -21,42 +24,163 @@ func (r *rsiLowReverse) AddPoint(calcValue float64, addValue float64) {
 
 }
 
-func (r *rsiLowReverse) IsPreviousLow() bool {
+// Value returns 1 if previous point is pivot
+func (r *rsiReverse) Value() (float64, bool) {
+
+	if r.lastRSIValues[0] < 0 {
+		return 0, false
+	}
+
+	if r.isPreviousPivot() {
+		return 1, true
+	}
+
+	return 0, true
+}
+
+func (r *rsiReverse) clone() rsiReverse {
+
+	lastValues := make([]float64, len(r.lastRSIValues))
+	copy(lastValues, r.lastRSIValues)
+
+	return rsiReverse{lastRSIValues: lastValues, high: r.high}
+}
+
+func (r *rsiReverse) Snapshot() IndicatorSnapshot {
+	return r.clone().lastRSIValues
+}
+
+func (r *rsiReverse) Restore(snapshot IndicatorSnapshot) error {
+
+	lastValues, ok := snapshot.([]float64)
+
+	if !ok || len(lastValues) != len(r.lastRSIValues) {
+		return errWrongSnapshot
+	}
+
+	copy(r.lastRSIValues, lastValues)
+
+	return nil
+}
+
+func (r *rsiReverse) name() string {
+
+	if r.high {
+		return "RSIHighReverse"
+	}
+
+	return "RSILowReverse"
+}
+
+func (r *rsiReverse) Marshal() ([]byte, error) {
+	return marshalState(r.name(), 1, r.lastRSIValues)
+}
+
+func (r *rsiReverse) Unmarshal(data []byte) error {
+	return unmarshalState(data, r.name(), 1, &r.lastRSIValues)
+}
+
+func (r *rsiReverse) isPreviousPivot() bool {
 
 	//if not values filled,we can get value
 	if r.lastRSIValues[0] < 0 {
 		return false
 	}
 
-	return r.lastRSIValues[1] <= r.lastRSIValues[0] && r.lastRSIValues[1] < r.lastRSIValues[2]
+	before, pivot, after := r.lastRSIValues[0], r.lastRSIValues[1], r.lastRSIValues[2]
+
+	if r.high {
+		before, pivot, after = -before, -pivot, -after
+	}
+
+	return pivot <= before && pivot < after
+}
+
+type rsiLowReverse struct {
+	rsiReverse
+}
+
+func (r *rsiLowReverse) Clone() Indicator {
+	return &rsiLowReverse{rsiReverse: r.clone()}
+}
+
+func (r *rsiLowReverse) IsPreviousLow() bool {
+	return r.isPreviousPivot()
 }
 
 func NewRSILowReverseIndicator() ReverseLowInterface {
 
 	lastValues := []float64{-1, -1, -1}
 
-	return &rsiLowReverse{lastRSIValues: lastValues}
+	return &rsiLowReverse{rsiReverse: rsiReverse{lastRSIValues: lastValues}}
 
 }
 
-func GenerateMapLows(lowReverse ReverseLowInterface, candles []candlescommon.KLine) map[int]struct{} {
+// pivotPrice is low of candle for lows and high for highs
+func pivotPrice(kline candlescommon.KLine, high bool) float64 {
+
+	if high {
+		return kline.HighPrice
+	}
+
+	return kline.LowPrice
+}
+
+// isReversalCandle is bullish candle with lower low than previous candle for lows,
+// bearish candle with higher high for highs
+func isReversalCandle(prevPrice float64, kline candlescommon.KLine, high bool) bool {
 
-	lowsMap := make(map[int]struct{})
+	if high {
+		return kline.OpenPrice >= kline.ClosePrice && prevPrice < kline.HighPrice
+	}
+
+	return kline.OpenPrice <= kline.ClosePrice && prevPrice > kline.LowPrice
+}
+
+// generateMapPivots marks previous candle found by reverse or reversal candle itself
+func generateMapPivots(reverse Indicator, isPrevious func() bool, candles []candlescommon.KLine, high bool) map[int]struct{} {
+
+	pivotsMap := make(map[int]struct{})
 
 	for idx, candle := range candles {
 
-		lowReverse.AddPoint(candle.LowPrice, 0)
+		reverse.AddPoint(pivotPrice(candle, high))
+
+		if isPrevious() {
+
+			pivotsMap[idx-1] = struct{}{}
+
+		} else if idx > 0 && isReversalCandle(pivotPrice(candles[idx-1], high), candle, high) {
+			pivotsMap[idx] = struct{}{}
+		}
+
+	}
+
+	return pivotsMap
+}
+
+func GenerateMapLows(lowReverse ReverseLowInterface, candles []candlescommon.KLine) map[int]struct{} {
+
+	//candle detectors report how many candles back confirmed lows are
+	if detector, ok := lowReverse.(PivotLowDetector); ok {
+
+		lowsMap := make(map[int]struct{})
+
+		for idx, candle := range candles {
 
-		if lowReverse.IsPreviousLow() {
+			detector.AddCandle(candle)
 
-			lowsMap[idx-1] = struct{}{}
+			for _, offset := range detector.ConfirmedLows() {
 
-		} else if idx > 0 && candle.OpenPrice <= candle.ClosePrice && candles[idx-1].LowPrice > candle.LowPrice {
-			lowsMap[idx] = struct{}{}
+				if idx-offset >= 0 {
+					lowsMap[idx-offset] = struct{}{}
+				}
+			}
 		}
 
+		return lowsMap
 	}
 
-	return lowsMap
+	return generateMapPivots(lowReverse, lowReverse.IsPreviousLow, candles, false)
 
 }
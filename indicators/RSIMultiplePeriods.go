@@ -1,9 +1,50 @@
 package indicators
 
 import (
+	"fmt"
+	"github.com/NERON/tran/candlescommon"
 	"math"
+	"sort"
+	"strconv"
+	"strings"
 )
 
+// DefaultMaxPeriod is the longest period of default ladder
+const DefaultMaxPeriod = 250
+
+// MaxLadderPeriod is the longest period and MaxLadderPeriodsCount is the most periods of custom period set
+const (
+	MaxLadderPeriod       = 1000
+	MaxLadderPeriodsCount = 500
+)
+
+// LadderPosition is position of price relative to zones of all periods
+type LadderPosition int
+
+const (
+	LadderInside LadderPosition = iota
+	LadderAbove
+	LadderBelow
+	LadderUndefined
+)
+
+func (position LadderPosition) String() string {
+
+	switch position {
+
+	case LadderInside:
+		return "inside"
+
+	case LadderAbove:
+		return "above"
+
+	case LadderBelow:
+		return "below"
+	}
+
+	return "undefined"
+}
+
 func round(num float64) int {
 	return int(num + math.Copysign(0.5, num))
 }
@@ -13,32 +54,374 @@ func toFixed(num float64, precision int) float64 {
 	return float64(round(num*output)) / output
 }
 
+// RSIMultiplePeriods calculates RSI for many periods over the same points,
+// state of all periods is kept in parallel arrays so one point updates them in one loop
 type RSIMultiplePeriods struct {
+
+	//empty smoothing is Wilder and empty source is close price
+	Smoothing string `json:",omitempty"`
+	Source    string `json:",omitempty"`
+
+	//periods in ascending order, averages have the same indexes
+	Periods   []uint
+	AvgGains  []float64
+	AvgLosses []float64
+
+	//last gains and losses of the longest period for Cutler's RSI, the newest is the last
+	Gains  []float64 `json:",omitempty"`
+	Losses []float64 `json:",omitempty"`
+
+	PointsCount uint
+	LastValue   float64
+
+	//zones of the current state, every point changes them
+	ladder rsiLadder
+}
+
+// rsiMultiplePeriodsV1 is state layout with separate RSI for every period
+type rsiMultiplePeriodsV1 struct {
 	RSIs []RSI
 }
 
+func (rsip *RSIMultiplePeriods) retainFactor(period uint) float64 {
+
+	if rsip.Smoothing == RSISmoothingEMA {
+		return float64(period-1) / 2
+	}
+
+	return float64(period - 1)
+}
+
+func (rsip *RSIMultiplePeriods) maxPeriod() uint {
+
+	if len(rsip.Periods) == 0 {
+		return 0
+	}
+
+	return rsip.Periods[len(rsip.Periods)-1]
+}
+
 func (rsip *RSIMultiplePeriods) AddPoint(addPrice float64) {
 
-	for i := 0; i < len(rsip.RSIs); i++ {
+	rsip.ladder.valid = false
+	rsip.PointsCount++
+
+	if rsip.PointsCount == 1 {
+		rsip.LastValue = addPrice
+		return
+	}
+
+	gain := math.Max(addPrice-rsip.LastValue, 0)
+	loss := math.Max(rsip.LastValue-addPrice, 0)
+
+	rsip.LastValue = addPrice
+
+	if rsip.Smoothing == RSISmoothingSMA {
+		rsip.addSMA(gain, loss)
+		return
+	}
+
+	for i, period := range rsip.Periods {
+
+		if rsip.PointsCount <= period+1 {
+
+			rsip.AvgGains[i] += gain
+			rsip.AvgLosses[i] += loss
+
+			if rsip.PointsCount == period+1 {
+				rsip.AvgGains[i] /= float64(period)
+				rsip.AvgLosses[i] /= float64(period)
+			}
+
+			continue
+		}
+
+		retain := rsip.retainFactor(period)
+
+		rsip.AvgGains[i] = (retain*rsip.AvgGains[i] + gain) / (retain + 1)
+		rsip.AvgLosses[i] = (retain*rsip.AvgLosses[i] + loss) / (retain + 1)
+	}
+}
+
+// addSMA recalculates window sums of all periods in one pass from the newest point
+func (rsip *RSIMultiplePeriods) addSMA(gain float64, loss float64) {
+
+	rsip.Gains = append(rsip.Gains, gain)
+	rsip.Losses = append(rsip.Losses, loss)
+
+	if len(rsip.Gains) > int(rsip.maxPeriod()) {
+		rsip.Gains = rsip.Gains[1:]
+		rsip.Losses = rsip.Losses[1:]
+	}
+
+	sumGain := 0.0
+	sumLoss := 0.0
+	taken := 0
+	last := len(rsip.Gains) - 1
+
+	for i, period := range rsip.Periods {
+
+		if rsip.PointsCount < period+1 {
+			break
+		}
+
+		for ; taken < int(period); taken++ {
+			sumGain += rsip.Gains[last-taken]
+			sumLoss += rsip.Losses[last-taken]
+		}
+
+		rsip.AvgGains[i] = sumGain / float64(period)
+		rsip.AvgLosses[i] = sumLoss / float64(period)
+	}
+}
+
+// AddCandle adds candle price selected by Source
+func (rsip *RSIMultiplePeriods) AddCandle(kline candlescommon.KLine) {
+	rsip.AddPoint(SourcePrice(kline, rsip.Source))
+}
+
+// periodIndex returns index of period in arrays
+func (rsip *RSIMultiplePeriods) periodIndex(period int) (int, bool) {
+
+	idx := sort.Search(len(rsip.Periods), func(i int) bool {
+		return int(rsip.Periods[i]) >= period
+	})
+
+	return idx, idx < len(rsip.Periods) && int(rsip.Periods[idx]) == period
+}
+
+func (rsip *RSIMultiplePeriods) calculate(idx int) (float64, bool) {
+
+	if rsip.PointsCount < rsip.Periods[idx]+1 {
+		return 0, false
+	}
+
+	return rsiFromGainLoss(rsip.AvgGains[idx], rsip.AvgLosses[idx]), true
+}
+
+// GetRSI returns RSI value for period
+func (rsip *RSIMultiplePeriods) GetRSI(period int) (float64, bool) {
+
+	idx, ok := rsip.periodIndex(period)
+
+	if !ok {
+		return 0, false
+	}
+
+	return rsip.calculate(idx)
+}
+
+// Value returns RSI for the largest period
+func (rsip *RSIMultiplePeriods) Value() (float64, bool) {
+
+	if len(rsip.Periods) == 0 {
+		return 0, false
+	}
+
+	return rsip.calculate(len(rsip.Periods) - 1)
+}
+
+func (rsip *RSIMultiplePeriods) clone() *RSIMultiplePeriods {
+
+	clone := *rsip
+
+	clone.ladder = rsiLadder{}
+	clone.Periods = append([]uint(nil), rsip.Periods...)
+	clone.AvgGains = append([]float64(nil), rsip.AvgGains...)
+	clone.AvgLosses = append([]float64(nil), rsip.AvgLosses...)
+
+	if rsip.Gains != nil {
+		clone.Gains = append(make([]float64, 0, rsip.maxPeriod()), rsip.Gains...)
+		clone.Losses = append(make([]float64, 0, rsip.maxPeriod()), rsip.Losses...)
+	}
+
+	return &clone
+}
+
+// CopyFrom sets state of source reusing arrays of rsip, ladder is calculated again for the copied state
+func (rsip *RSIMultiplePeriods) CopyFrom(source *RSIMultiplePeriods) {
+
+	rsip.Smoothing = source.Smoothing
+	rsip.Source = source.Source
+	rsip.PointsCount = source.PointsCount
+	rsip.LastValue = source.LastValue
+
+	rsip.Periods = append(rsip.Periods[:0], source.Periods...)
+	rsip.AvgGains = append(rsip.AvgGains[:0], source.AvgGains...)
+	rsip.AvgLosses = append(rsip.AvgLosses[:0], source.AvgLosses...)
+
+	if source.Gains == nil {
+
+		rsip.Gains, rsip.Losses = nil, nil
+
+	} else {
+
+		rsip.Gains = append(rsip.Gains[:0], source.Gains...)
+		rsip.Losses = append(rsip.Losses[:0], source.Losses...)
+	}
+
+	rsip.ladder.valid = false
+}
+
+func (rsip *RSIMultiplePeriods) Clone() Indicator {
+	return rsip.clone()
+}
+
+func (rsip *RSIMultiplePeriods) Snapshot() IndicatorSnapshot {
+	return rsip.clone()
+}
+
+func (rsip *RSIMultiplePeriods) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*RSIMultiplePeriods)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*rsip = *state.clone()
+
+	return nil
+}
+
+func (rsip *RSIMultiplePeriods) Marshal() ([]byte, error) {
+	return marshalState("RSIMultiplePeriods", 2, rsip)
+}
+
+func (rsip *RSIMultiplePeriods) Unmarshal(data []byte) error {
+
+	var legacy rsiMultiplePeriodsV1
+
+	err := unmarshalState(data, "RSIMultiplePeriods", 2, &legacy)
+
+	if err != nil {
+		return err
+	}
+
+	//state saved with separate RSIs is converted to arrays
+	if len(legacy.RSIs) > 0 {
+		*rsip = *newRSIMultiplePeriodsFromRSIs(legacy.RSIs)
+		return nil
+	}
+
+	return unmarshalState(data, "RSIMultiplePeriods", 2, rsip)
+}
+
+func newRSIMultiplePeriodsFromRSIs(RSIs []RSI) *RSIMultiplePeriods {
+
+	rsip := &RSIMultiplePeriods{
+		Smoothing:   RSIs[0].Smoothing,
+		Source:      RSIs[0].Source,
+		Periods:     make([]uint, len(RSIs)),
+		AvgGains:    make([]float64, len(RSIs)),
+		AvgLosses:   make([]float64, len(RSIs)),
+		PointsCount: RSIs[0].PointsCount,
+		LastValue:   RSIs[0].LastValue,
+	}
+
+	for i := 0; i < len(RSIs); i++ {
+
+		rsip.Periods[i] = RSIs[i].Period
+		rsip.AvgGains[i] = RSIs[i].AvgGain
+		rsip.AvgLosses[i] = RSIs[i].AvgLoss
+	}
+
+	//window of the longest period contains windows of all others
+	if rsip.Smoothing == RSISmoothingSMA {
+
+		rsip.Gains = append([]float64(nil), RSIs[len(RSIs)-1].Gains...)
+		rsip.Losses = append([]float64(nil), RSIs[len(RSIs)-1].Losses...)
+	}
+
+	return rsip
+}
+
+// retainedGainLoss returns gain and loss sums that stay in average of period after next point,
+// ok is false if period isn't ready even after next point
+func (rsip *RSIMultiplePeriods) retainedGainLoss(idx int) (float64, float64, bool) {
+
+	period := rsip.Periods[idx]
+
+	if rsip.PointsCount < period {
+		return 0, 0, false
+	}
+
+	if rsip.Smoothing == RSISmoothingSMA {
+
+		gain := 0.0
+		loss := 0.0
+		last := len(rsip.Gains) - 1
+
+		for i := 0; i < int(period)-1; i++ {
+			gain += rsip.Gains[last-i]
+			loss += rsip.Losses[last-i]
+		}
+
+		return gain, loss, true
+	}
+
+	//averages still hold sums of changes before the first value
+	if rsip.PointsCount == period {
+		return rsip.AvgGains[idx], rsip.AvgLosses[idx], true
+	}
+
+	retain := rsip.retainFactor(period)
+
+	return retain * rsip.AvgGains[idx], retain * rsip.AvgLosses[idx], true
+}
+
+// predictForNextPoint returns RSI of period if next point is value
+func (rsip *RSIMultiplePeriods) predictForNextPoint(idx int, value float64) (float64, bool) {
+
+	retainedGain, retainedLoss, ok := rsip.retainedGainLoss(idx)
+
+	if !ok || rsip.PointsCount == 0 {
+		return 0, false
+	}
+
+	//both averages are divided by the same number, so it's enough to compare sums
+	return rsiFromGainLoss(retainedGain+math.Max(value-rsip.LastValue, 0), retainedLoss+math.Max(rsip.LastValue-value, 0)), true
+}
+
+// predictPrice returns price of next point that gives RSIValue for period
+func (rsip *RSIMultiplePeriods) predictPrice(idx int, RSIValue float64) (float64, PredictionStatus) {
+
+	if rsip.PointsCount < rsip.Periods[idx]+1 {
+		return 0, PredictionNotReady
+	}
+
+	retainedGain, retainedLoss, _ := rsip.retainedGainLoss(idx)
+
+	return predictPriceFromRetained(retainedGain, retainedLoss, rsip.LastValue, RSIValue)
+}
+
+// PredictPrice returns price of next point that gives RSIValue for period
+func (rsip *RSIMultiplePeriods) PredictPrice(period int, RSIValue float64) (float64, PredictionStatus) {
 
-		rsip.RSIs[i].AddPoint(addPrice)
+	idx, ok := rsip.periodIndex(period)
+
+	if !ok {
+		return 0, PredictionNotReady
 	}
+
+	return rsip.predictPrice(idx, RSIValue)
 }
+
 func (rsip *RSIMultiplePeriods) GetBestPeriodByRSIValue(priceFor float64, centralRSI float64) int {
 
 	BestRSIDiff := 99999.0
 	bestPeriod := 0
 
-	for i := 0; i < len(rsip.RSIs); i++ {
+	for i := 0; i < len(rsip.Periods); i++ {
 
-		RSIValue, ok := rsip.RSIs[i].PredictForNextPoint(priceFor)
+		RSIValue, ok := rsip.predictForNextPoint(i, priceFor)
 
 		if !ok {
 			return bestPeriod
 		}
 
 		if math.Abs(RSIValue-centralRSI) < BestRSIDiff {
-			bestPeriod = int(rsip.RSIs[i].Period)
+			bestPeriod = int(rsip.Periods[i])
 			BestRSIDiff = math.Abs(RSIValue - centralRSI)
 		}
 	}
@@ -46,59 +429,319 @@ func (rsip *RSIMultiplePeriods) GetBestPeriodByRSIValue(priceFor float64, centra
 	return bestPeriod
 
 }
+
+// GetBestPeriod returns the longest period which zone contains price, period 1 is returned
+// if price is outside of the ladder
 func (rsip *RSIMultiplePeriods) GetBestPeriod(priceFor float64, centralRSI float64) (int, float64, float64) {
 
-	bestPeriod := 1
+	period, central, position := rsip.FindPeriod(priceFor, centralRSI)
+
+	if position != LadderInside {
+		return 1, 0, 0
+	}
+
+	return period, 0, central
+}
+
+// GetBestPeriodHigh is GetBestPeriod for highs and overbought central RSI
+func (rsip *RSIMultiplePeriods) GetBestPeriodHigh(priceFor float64, centralRSI float64) (int, float64, float64) {
+
+	period, central, position := rsip.FindPeriodHigh(priceFor, centralRSI)
+
+	if position != LadderInside {
+		return 1, 0, 0
+	}
+
+	return period, 0, central
+}
+
+// FindPeriod returns the longest period which zone contains price and position of price relative to the ladder.
+// Zones are searched among all periods except the first and the last, they only bound neighbour zones.
+// Zones are calculated once for every RSI state, in parts of the ladder where zones move in one direction
+// the zone is found by binary search and parts where direction changes are scanned
+func (rsip *RSIMultiplePeriods) FindPeriod(priceFor float64, centralRSI float64) (int, float64, LadderPosition) {
+	return rsip.findPeriod(priceFor, centralRSI, false)
+}
+
+// FindPeriodHigh is FindPeriod for highs, with overbought central RSI the ladder goes up with period
+// and zones are resistance zones above price
+func (rsip *RSIMultiplePeriods) FindPeriodHigh(priceFor float64, centralRSI float64) (int, float64, LadderPosition) {
+	return rsip.findPeriod(priceFor, centralRSI, true)
+}
+
+func (rsip *RSIMultiplePeriods) findPeriod(priceFor float64, centralRSI float64, high bool) (int, float64, LadderPosition) {
+
+	if len(rsip.Periods) <= 2 {
+		return 0, 0, LadderUndefined
+	}
+
+	ladder := rsip.zonesLadder(centralRSI)
+
+	if idx, ok := ladder.find(priceFor, high); ok {
+		return int(rsip.Periods[idx]), ladder.central[idx], LadderInside
+	}
+
+	return 0, 0, ladder.position(priceFor, high)
+}
+
+// findPeriodLinear scans zones of all periods, it's the rule of FindPeriod without ladder of zones
+func (rsip *RSIMultiplePeriods) findPeriodLinear(priceFor float64, centralRSI float64, high bool) (int, float64, LadderPosition) {
+
+	bestPeriod := 0
 	centralV := 0.0
 
-	for i := 2; i < len(rsip.RSIs); i++ {
+	highest := math.Inf(-1)
+	lowest := math.Inf(1)
+
+	for i := 1; i < len(rsip.Periods)-1; i++ {
 
-		up, down, central := rsip.GetIntervalForPeriod(i, centralRSI)
+		up, down, central, ok := rsip.zoneForIndex(i, centralRSI, high)
+
+		//period without zone can't be the best one
+		if !ok {
+			continue
+		}
+
+		highest = math.Max(highest, up)
+		lowest = math.Min(lowest, down)
 
 		if priceFor <= up && priceFor >= down {
-			bestPeriod = i
+			bestPeriod = int(rsip.Periods[i])
 			centralV = central
 		}
 	}
 
-	return bestPeriod, 0, centralV
+	if bestPeriod > 0 {
+		return bestPeriod, centralV, LadderInside
+	}
+
+	if priceFor > highest && !math.IsInf(highest, -1) {
+		return 0, 0, LadderAbove
+	}
+
+	if priceFor < lowest && !math.IsInf(lowest, 1) {
+		return 0, 0, LadderBelow
+	}
+
+	return 0, 0, LadderUndefined
 }
 
 func (rsip *RSIMultiplePeriods) GetIntervalForPeriod(period int, centralRSI float64) (float64, float64, float64) {
 
-	central, _ := rsip.RSIs[period-1].PredictPrice(centralRSI)
-	upBorder := central
-	lowBorder := central
+	up, down, central, _ := rsip.GetIntervalForPeriodStatus(period, centralRSI)
+
+	return up, down, central
+}
+
+// GetIntervalForPeriodHigh returns resistance zone of period for overbought central RSI
+func (rsip *RSIMultiplePeriods) GetIntervalForPeriodHigh(period int, centralRSI float64) (float64, float64, float64) {
+
+	up, down, central, _ := rsip.zoneForPeriod(period, centralRSI, true)
+
+	return up, down, central
+}
+
+// GetIntervalForPeriodStatus returns zone of period, ok is false if central price of period
+// can't be predicted and zone is empty
+func (rsip *RSIMultiplePeriods) GetIntervalForPeriodStatus(period int, centralRSI float64) (float64, float64, float64, bool) {
+	return rsip.zoneForPeriod(period, centralRSI, false)
+}
+
+func (rsip *RSIMultiplePeriods) zoneForPeriod(period int, centralRSI float64, high bool) (float64, float64, float64, bool) {
+
+	idx, ok := rsip.periodIndex(period)
+
+	if !ok {
+		return 0, 0, 0, false
+	}
+
+	return rsip.zoneForIndex(idx, centralRSI, high)
+}
+
+// zoneForIndex returns zone between middles of central price and central prices of neighbour periods,
+// for lows shorter period bounds zone from above and for highs from below
+func (rsip *RSIMultiplePeriods) zoneForIndex(idx int, centralRSI float64, high bool) (float64, float64, float64, bool) {
+
+	central, status := rsip.predictPrice(idx, centralRSI)
+
+	if status != PredictionOK {
+		return 0, 0, 0, false
+	}
 
-	if period > 1 {
+	shorterBorder := central
+	longerBorder := central
 
-		Val, _ := rsip.RSIs[period-2].PredictPrice(centralRSI)
+	if idx > 0 {
 
-		upBorder = (upBorder + Val) / 2
+		Val, ok := rsip.neighbourPrice(idx-1, centralRSI)
+
+		if ok {
+			shorterBorder = (shorterBorder + Val) / 2
+		}
 
 	}
 
-	if period < len(rsip.RSIs) {
+	if idx < len(rsip.Periods)-1 {
 
-		Val, ok := rsip.RSIs[period].PredictPrice(centralRSI)
+		Val, ok := rsip.neighbourPrice(idx+1, centralRSI)
 
 		if ok {
-			lowBorder = (lowBorder + Val) / 2
+			longerBorder = (longerBorder + Val) / 2
 		}
 
 	}
 
-	return upBorder, lowBorder, central
+	if high {
+		return longerBorder, shorterBorder, central, true
+	}
+
+	return shorterBorder, longerBorder, central, true
+
+}
+
+// neighbourPrice returns predicted price of period by index, when RSI value is unreachable
+// with one point (like for period 1) the limit of such move is the last price
+func (rsip *RSIMultiplePeriods) neighbourPrice(idx int, centralRSI float64) (float64, bool) {
+	return rsip.neighbourFromPrediction(rsip.predictPrice(idx, centralRSI))
+}
+
+func (rsip *RSIMultiplePeriods) neighbourFromPrediction(price float64, status PredictionStatus) (float64, bool) {
+
+	if status == PredictionUnreachable {
+		return rsip.LastValue, true
+	}
+
+	return price, status == PredictionOK
+}
+
+// NextPeriod returns the period that follows period in the ladder
+func (rsip *RSIMultiplePeriods) NextPeriod(period int) (int, bool) {
+
+	idx := sort.Search(len(rsip.Periods), func(i int) bool {
+		return int(rsip.Periods[i]) > period
+	})
+
+	if idx == len(rsip.Periods) {
+		return 0, false
+	}
 
+	return int(rsip.Periods[idx]), true
+}
+
+// MaxPeriod returns the longest period of the ladder
+func (rsip *RSIMultiplePeriods) MaxPeriod() int {
+	return int(rsip.maxPeriod())
 }
 
 func NewRSIMultiplePeriods(maxPeriod int) *RSIMultiplePeriods {
+	return NewRSIMultiplePeriodsWithSettings(maxPeriod, RSISmoothingWilder, PriceSourceClose)
+}
+
+// NewRSIMultiplePeriodsWithSettings creates RSIs for periods from 1 to maxPeriod
+func NewRSIMultiplePeriodsWithSettings(maxPeriod int, smoothing string, source string) *RSIMultiplePeriods {
 
-	RSIs := make([]RSI, maxPeriod)
+	periods := make([]uint, 0, maxPeriod)
 
-	for i := 0; i < maxPeriod; i++ {
-		RSIs[i] = RSI{Period: uint(i + 1)}
+	for i := 1; i <= maxPeriod; i++ {
+		periods = append(periods, uint(i))
+	}
+
+	return NewRSIMultiplePeriodsWithPeriods(periods, smoothing, source)
+}
+
+// NewRSIMultiplePeriodsWithPeriods creates RSIs with the same smoothing and price source for any set of periods
+func NewRSIMultiplePeriodsWithPeriods(periods []uint, smoothing string, source string) *RSIMultiplePeriods {
+
+	sorted := make([]uint, 0, len(periods))
+
+	for _, period := range periods {
+
+		if period > 0 {
+			sorted = append(sorted, period)
+		}
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	//duplicates are removed
+	unique := sorted[:0]
+
+	for i, period := range sorted {
+
+		if i == 0 || period != sorted[i-1] {
+			unique = append(unique, period)
+		}
+	}
+
+	return &RSIMultiplePeriods{
+		Smoothing: smoothing,
+		Source:    source,
+		Periods:   unique,
+		AvgGains:  make([]float64, len(unique)),
+		AvgLosses: make([]float64, len(unique)),
+	}
+}
+
+// ParsePeriodSet parses periods like 1-50,55-500/5 where /5 is step of range,
+// periods should be from 1 to MaxLadderPeriod and there should be no more than MaxLadderPeriodsCount of them
+func ParsePeriodSet(description string) ([]uint, error) {
+
+	periods := make([]uint, 0)
+	count := uint64(0)
+
+	for _, part := range strings.Split(description, ",") {
+
+		step := uint64(1)
+
+		if slash := strings.Index(part, "/"); slash >= 0 {
+
+			var err error
+
+			step, err = strconv.ParseUint(part[slash+1:], 10, 64)
+
+			if err != nil || step == 0 {
+				return nil, fmt.Errorf("wrong step in periods %s", part)
+			}
+
+			part = part[:slash]
+		}
+
+		bounds := strings.SplitN(part, "-", 2)
+
+		from, err := strconv.ParseUint(bounds[0], 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("wrong periods %s", part)
+		}
+
+		to := from
+
+		if len(bounds) == 2 {
+
+			to, err = strconv.ParseUint(bounds[1], 10, 64)
+
+			if err != nil {
+				return nil, fmt.Errorf("wrong periods %s", part)
+			}
+		}
+
+		if from == 0 || from > to || to > MaxLadderPeriod {
+			return nil, fmt.Errorf("periods %s should be from 1 to %d", part, MaxLadderPeriod)
+		}
+
+		count += (to-from)/step + 1
+
+		if count > MaxLadderPeriodsCount {
+			return nil, fmt.Errorf("periods set should have no more than %d periods", MaxLadderPeriodsCount)
+		}
+
+		//to isn't more than MaxLadderPeriod, so period doesn't overflow
+		for period := from; period <= to; period += step {
+			periods = append(periods, uint(period))
+		}
 	}
 
-	return &RSIMultiplePeriods{RSIs: RSIs}
+	return periods, nil
 }
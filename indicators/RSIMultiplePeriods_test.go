@@ -0,0 +1,113 @@
+package indicators
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/NERON/tran/candlescommon"
+)
+
+// randomKLines returns random walk candles, the same seed gives the same candles
+func randomKLines(seed int64, count int) []candlescommon.KLine {
+
+	random := rand.New(rand.NewSource(seed))
+
+	klines := make([]candlescommon.KLine, 0, count)
+	price := 100.0
+
+	for i := 0; i < count; i++ {
+
+		open := price
+		price *= math.Exp(random.NormFloat64() * 0.01)
+
+		high := math.Max(open, price) * (1 + random.Float64()*0.005)
+		low := math.Min(open, price) * (1 - random.Float64()*0.005)
+
+		klines = append(klines, candlescommon.KLine{OpenTime: uint64(i) * 60000, CloseTime: uint64(i)*60000 + 59999, OpenPrice: open, ClosePrice: price, HighPrice: high, LowPrice: low, Closed: true})
+	}
+
+	return klines
+}
+
+func TestFindPeriodMatchesLinearScan(t *testing.T) {
+
+	random := rand.New(rand.NewSource(1))
+
+	cases := []struct {
+		smoothing  string
+		centralRSI float64
+		high       bool
+	}{
+		{RSISmoothingWilder, 15, false},
+		{RSISmoothingWilder, 20, false},
+		{RSISmoothingEMA, 15, false},
+		{RSISmoothingSMA, 20, false},
+		{RSISmoothingWilder, 85, true},
+		{RSISmoothingEMA, 80, true},
+	}
+
+	for _, testCase := range cases {
+
+		for seed := int64(0); seed < 3; seed++ {
+
+			rsip := NewRSIMultiplePeriodsWithSettings(DefaultMaxPeriod, testCase.smoothing, PriceSourceClose)
+
+			for idx, kline := range randomKLines(seed, 1000) {
+
+				rsip.AddCandle(kline)
+
+				if idx < 50 {
+					continue
+				}
+
+				for i := 0; i < 10; i++ {
+
+					price := kline.ClosePrice * (1 + (random.Float64()-0.5)*0.2)
+
+					period, central, position := rsip.findPeriod(price, testCase.centralRSI, testCase.high)
+					linearPeriod, linearCentral, linearPosition := rsip.findPeriodLinear(price, testCase.centralRSI, testCase.high)
+
+					if period != linearPeriod || central != linearCentral || position != linearPosition {
+						t.Fatalf("%s %.0f high=%v seed %d candle %d price %f: got %d %f %s, linear scan %d %f %s", testCase.smoothing, testCase.centralRSI, testCase.high, seed, idx, price, period, central, position, linearPeriod, linearCentral, linearPosition)
+					}
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkAddCandle(b *testing.B) {
+
+	klines := randomKLines(1, 1000)
+	rsip := NewRSIMultiplePeriods(DefaultMaxPeriod)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rsip.AddCandle(klines[i%len(klines)])
+	}
+}
+
+func BenchmarkFindPeriod(b *testing.B) {
+
+	klines := randomKLines(1, 1000)
+	rsip := NewRSIMultiplePeriods(DefaultMaxPeriod)
+
+	for _, kline := range klines {
+		rsip.AddCandle(kline)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+
+		//every candle changes the ladder
+		kline := klines[i%len(klines)]
+
+		rsip.AddCandle(kline)
+		rsip.FindPeriod(kline.LowPrice, 15)
+	}
+}
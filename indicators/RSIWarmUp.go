@@ -0,0 +1,42 @@
+package indicators
+
+import (
+	"math"
+)
+
+// DefaultWarmUpTolerance is weight of initial average that may remain in RSI after warm-up
+const DefaultWarmUpTolerance = 0.001
+
+// WarmUpPoints returns number of points after which RSI of period doesn't depend on the first
+// points more than tolerance. Exponential smoothing forgets the seed average as power of retain weight,
+// Cutler's RSI depends only on its window
+func WarmUpPoints(period int, smoothing string, tolerance float64) int {
+
+	if tolerance <= 0 || tolerance >= 1 {
+		tolerance = DefaultWarmUpTolerance
+	}
+
+	if period <= 1 || smoothing == RSISmoothingSMA {
+		return period + 1
+	}
+
+	retain := float64(period - 1)
+
+	if smoothing == RSISmoothingEMA {
+		retain /= 2
+	}
+
+	decay := retain / (retain + 1)
+
+	return period + 1 + int(math.Ceil(math.Log(tolerance)/math.Log(decay)))
+}
+
+// WarmUpPoints returns number of points needed for the longest period to converge
+func (rsip *RSIMultiplePeriods) WarmUpPoints(tolerance float64) int {
+	return WarmUpPoints(rsip.MaxPeriod(), rsip.Smoothing, tolerance)
+}
+
+// IsWarmedUp returns true if enough points were added for the longest period to converge
+func (rsip *RSIMultiplePeriods) IsWarmedUp(tolerance float64) bool {
+	return int(rsip.PointsCount) >= rsip.WarmUpPoints(tolerance)
+}
@@ -0,0 +1,326 @@
+package indicators
+
+import (
+	"github.com/NERON/tran/candlescommon"
+	"math"
+)
+
+// CandleIndicator is implemented by indicators that need whole candle instead of one price
+type CandleIndicator interface {
+	AddCandle(kline candlescommon.KLine)
+}
+
+// BandIndicator is implemented by indicators that have upper and lower band around middle value
+type BandIndicator interface {
+	Bands() (float64, float64, float64, bool)
+}
+
+// AddCandleToIndicator passes candle to indicator, close price is used if indicator works with prices only
+func AddCandleToIndicator(indicator Indicator, kline candlescommon.KLine) {
+
+	if candleIndicator, ok := indicator.(CandleIndicator); ok {
+		candleIndicator.AddCandle(kline)
+		return
+	}
+
+	indicator.AddPoint(kline.ClosePrice)
+}
+
+// ATR is Wilder average of true range
+type ATR struct {
+	Period    uint
+	PrevClose float64
+	HasPrev   bool
+	Average   *MovingAverage
+}
+
+func (atr *ATR) AddCandle(kline candlescommon.KLine) {
+
+	trueRange := kline.HighPrice - kline.LowPrice
+
+	if atr.HasPrev {
+		trueRange = math.Max(trueRange, math.Abs(kline.HighPrice-atr.PrevClose))
+		trueRange = math.Max(trueRange, math.Abs(kline.LowPrice-atr.PrevClose))
+	}
+
+	atr.Average.AddPoint(trueRange)
+
+	atr.PrevClose = kline.ClosePrice
+	atr.HasPrev = true
+}
+
+// AddPoint uses value as close, high and low of candle
+func (atr *ATR) AddPoint(value float64) {
+	atr.AddCandle(candlescommon.KLine{OpenPrice: value, ClosePrice: value, HighPrice: value, LowPrice: value})
+}
+
+func (atr *ATR) Calculate() (float64, bool) {
+	return atr.Average.Calculate()
+}
+
+func (atr *ATR) Value() (float64, bool) {
+	return atr.Calculate()
+}
+
+// ToUnits converts price distance to ATR units
+func (atr *ATR) ToUnits(distance float64) (float64, bool) {
+
+	value, ok := atr.Calculate()
+
+	if !ok || value == 0 {
+		return 0, false
+	}
+
+	return distance / value, true
+}
+
+func (atr *ATR) clone() *ATR {
+
+	clone := *atr
+	clone.Average = atr.Average.clone()
+
+	return &clone
+}
+
+func (atr *ATR) Clone() Indicator {
+	return atr.clone()
+}
+
+func (atr *ATR) Snapshot() IndicatorSnapshot {
+	return atr.clone()
+}
+
+func (atr *ATR) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*ATR)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*atr = *state.clone()
+
+	return nil
+}
+
+func (atr *ATR) Marshal() ([]byte, error) {
+	return marshalState("ATR", 1, atr)
+}
+
+func (atr *ATR) Unmarshal(data []byte) error {
+	return unmarshalState(data, "ATR", 1, atr)
+}
+
+func NewATR(period uint) *ATR {
+	return &ATR{Period: period, Average: NewRMA(period)}
+}
+
+// BollingerBands are simple average with bands at Multiplier standard deviations
+type BollingerBands struct {
+	Multiplier float64
+	Average    *MovingAverage
+}
+
+func (bb *BollingerBands) AddPoint(value float64) {
+	bb.Average.AddPoint(value)
+}
+
+func (bb *BollingerBands) Bands() (float64, float64, float64, bool) {
+
+	middle, ok := bb.Average.Calculate()
+
+	if !ok {
+		return 0, 0, 0, false
+	}
+
+	variance := 0.0
+
+	for _, value := range bb.Average.Window {
+		variance += (value - middle) * (value - middle)
+	}
+
+	deviation := math.Sqrt(variance / float64(len(bb.Average.Window)))
+
+	return middle + bb.Multiplier*deviation, middle, middle - bb.Multiplier*deviation, true
+}
+
+func (bb *BollingerBands) Values() (map[string]float64, bool) {
+
+	upper, _, lower, ok := bb.Bands()
+
+	return map[string]float64{"upper": upper, "lower": lower}, ok
+}
+
+func (bb *BollingerBands) Value() (float64, bool) {
+	return bb.Average.Calculate()
+}
+
+func (bb *BollingerBands) clone() *BollingerBands {
+	return &BollingerBands{Multiplier: bb.Multiplier, Average: bb.Average.clone()}
+}
+
+func (bb *BollingerBands) Clone() Indicator {
+	return bb.clone()
+}
+
+func (bb *BollingerBands) Snapshot() IndicatorSnapshot {
+	return bb.clone()
+}
+
+func (bb *BollingerBands) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*BollingerBands)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*bb = *state.clone()
+
+	return nil
+}
+
+func (bb *BollingerBands) Marshal() ([]byte, error) {
+	return marshalState("BollingerBands", 1, bb)
+}
+
+func (bb *BollingerBands) Unmarshal(data []byte) error {
+	return unmarshalState(data, "BollingerBands", 1, bb)
+}
+
+func NewBollingerBands(period uint, multiplier float64) *BollingerBands {
+	return &BollingerBands{Multiplier: multiplier, Average: NewSMA(period)}
+}
+
+// KeltnerChannels are exponential average with bands at Multiplier ATR
+type KeltnerChannels struct {
+	Multiplier float64
+	Average    *MovingAverage
+	ATR        *ATR
+}
+
+func (kc *KeltnerChannels) AddCandle(kline candlescommon.KLine) {
+
+	kc.Average.AddPoint(kline.ClosePrice)
+	kc.ATR.AddCandle(kline)
+}
+
+func (kc *KeltnerChannels) AddPoint(value float64) {
+
+	kc.Average.AddPoint(value)
+	kc.ATR.AddPoint(value)
+}
+
+func (kc *KeltnerChannels) Bands() (float64, float64, float64, bool) {
+
+	middle, okMiddle := kc.Average.Calculate()
+	atr, okATR := kc.ATR.Calculate()
+
+	if !okMiddle || !okATR {
+		return 0, 0, 0, false
+	}
+
+	return middle + kc.Multiplier*atr, middle, middle - kc.Multiplier*atr, true
+}
+
+func (kc *KeltnerChannels) Values() (map[string]float64, bool) {
+
+	upper, _, lower, ok := kc.Bands()
+
+	return map[string]float64{"upper": upper, "lower": lower}, ok
+}
+
+func (kc *KeltnerChannels) Value() (float64, bool) {
+	return kc.Average.Calculate()
+}
+
+func (kc *KeltnerChannels) clone() *KeltnerChannels {
+	return &KeltnerChannels{Multiplier: kc.Multiplier, Average: kc.Average.clone(), ATR: kc.ATR.clone()}
+}
+
+func (kc *KeltnerChannels) Clone() Indicator {
+	return kc.clone()
+}
+
+func (kc *KeltnerChannels) Snapshot() IndicatorSnapshot {
+	return kc.clone()
+}
+
+func (kc *KeltnerChannels) Restore(snapshot IndicatorSnapshot) error {
+
+	state, ok := snapshot.(*KeltnerChannels)
+
+	if !ok {
+		return errWrongSnapshot
+	}
+
+	*kc = *state.clone()
+
+	return nil
+}
+
+func (kc *KeltnerChannels) Marshal() ([]byte, error) {
+	return marshalState("KeltnerChannels", 1, kc)
+}
+
+func (kc *KeltnerChannels) Unmarshal(data []byte) error {
+	return unmarshalState(data, "KeltnerChannels", 1, kc)
+}
+
+func NewKeltnerChannels(period uint, atrPeriod uint, multiplier float64) *KeltnerChannels {
+	return &KeltnerChannels{Multiplier: multiplier, Average: NewEMA(period), ATR: NewATR(atrPeriod)}
+}
+
+func init() {
+
+	RegisterIndicator("ATR", func(params []float64) (Indicator, error) {
+
+		period, err := periodParam(params, 0, 14)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewATR(period), nil
+	})
+
+	for _, name := range []string{"BollingerBands", "BB"} {
+
+		RegisterIndicator(name, func(params []float64) (Indicator, error) {
+
+			period, err := periodParam(params, 0, 20)
+
+			if err != nil {
+				return nil, err
+			}
+
+			multiplier, err := positiveParam(params, 1, 2)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return NewBollingerBands(period, multiplier), nil
+		})
+	}
+
+	for _, name := range []string{"KeltnerChannels", "KC"} {
+
+		RegisterIndicator(name, func(params []float64) (Indicator, error) {
+
+			periods, err := periodParams(params, 20, 10)
+
+			if err != nil {
+				return nil, err
+			}
+
+			multiplier, err := positiveParam(params, 2, 2)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return NewKeltnerChannels(periods[0], periods[1], multiplier), nil
+		})
+	}
+}
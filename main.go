@@ -1,11 +1,13 @@
 package main
 
 import (
+	"github.com/NERON/tran/candlescommon"
 	"github.com/NERON/tran/database"
 	"github.com/NERON/tran/manager"
 	"html/template"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
@@ -13,6 +15,21 @@ import (
 
 var TemplateManager *template.Template
 
+// defaultLiveSequences are kept live when tran_live_sequences has no rows
+func defaultLiveSequences() []manager.LiveSequenceConfig {
+
+	configs := make([]manager.LiveSequenceConfig, 0)
+
+	for _, centralRSI := range []uint{manager.DefaultCentralRSI, 20} {
+
+		for _, interval := range hourGroupIntervals {
+			configs = append(configs, manager.LiveSequenceConfig{Symbol: "ETHUSDT", Interval: interval, CentralRSI: centralRSI})
+		}
+	}
+
+	return configs
+}
+
 func InitRouting() *mux.Router {
 
 	r := mux.NewRouter()
@@ -24,6 +41,11 @@ func InitRouting() *mux.Router {
 	r.HandleFunc("/getDD/{symbol}/{centralRSI}/{mode}/{groupCount}/{timestamp}", NewGroupsHandler)
 	r.HandleFunc("/getInter/{symbol}/{centralRSI}", GetIntervalHandler)
 	r.HandleFunc("/getPeriodsNew/{symbol}/{interval}/{timestamp}/{centralRSI}", NewTesterHandler)
+	r.HandleFunc("/validate/{symbol}/{interval}", ValidateCandlesHandler)
+	r.HandleFunc("/index/{name}", IndexDefinitionHandler)
+	r.HandleFunc("/divergences/{symbol}/{interval}", DivergenceHandler)
+	r.HandleFunc("/sequenceAppearance/{symbol}/{interval}/{centralRSI}/{period}", SequenceAppearanceHandler)
+	r.HandleFunc("/sequenceTimeline/{symbol}/{interval}/{centralRSI}", SequenceTimelineHandler)
 
 	return r
 }
@@ -42,12 +64,63 @@ func main() {
 		log.Fatal("Database connection error: ", err.Error())
 	}
 
-	manager.KLineCacher, err = manager.NewLastKlinesCacher([]string{"ETHUSDT", "MFTETH"})
+	err = manager.LoadIndexDefinitions()
+
+	if err != nil {
+		log.Println("Index definitions load error: ", err.Error())
+	}
+
+	migrated, err := manager.MigrateSequenceSnapshots()
+
+	if err != nil {
+		log.Println("Sequence snapshots migration error: ", err.Error())
+	} else if migrated > 0 {
+		log.Println("Sequence snapshots migrated: ", migrated)
+	}
+
+	manager.StartSnapshotRetention(manager.DefaultSnapshotRetention, 6*time.Hour)
+
+	manager.RegisterSyntheticSymbol("MFTUSDT", "MFTETH", "ETHUSDT", candlescommon.SyntheticProduct)
+
+	liveSequences, err := manager.LoadLiveSequenceConfigs()
+
+	if err != nil {
+		log.Println("Live sequences load error: ", err.Error())
+	}
+
+	if len(liveSequences) == 0 {
+		liveSequences = defaultLiveSequences()
+	}
+
+	//symbols of live sequences are cached too, their candles are grouped from cached klines
+	cachedSymbols := []string{"ETHUSDT", "MFTETH"}
+
+	for _, config := range liveSequences {
+
+		cached := false
+
+		for _, symbol := range cachedSymbols {
+			cached = cached || symbol == config.Symbol
+		}
+
+		if !cached {
+			cachedSymbols = append(cachedSymbols, config.Symbol)
+		}
+	}
+
+	manager.KLineCacher, err = manager.NewLastKlinesCacher(cachedSymbols)
 
 	if err != nil {
 		log.Fatal(err.Error())
 	}
 
+	//groups of hour intervals are answered from live sequences, every central RSI level is kept side by side
+	manager.LiveSequencer = manager.NewLiveSequences(manager.KLineCacher, manager.DefaultLiveSnapshotInterval)
+
+	for _, config := range liveSequences {
+		manager.LiveSequencer.Track(config.Symbol, config.Interval, config.CentralRSI, config.Options)
+	}
+
 	router := InitRouting()
 
 	log.Fatal(http.ListenAndServe(":80",router))
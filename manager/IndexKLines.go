@@ -0,0 +1,513 @@
+package manager
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/NERON/tran/candlescommon"
+	"github.com/NERON/tran/database"
+	"log"
+	"math"
+	"sort"
+	"sync"
+)
+
+const (
+	IndexWeightEqual = "equal"
+	IndexWeightFixed = "fixed"
+	IndexWeightCap   = "cap"
+)
+
+var errIndexNoComponents = errors.New("index should have at least one component")
+var errIndexWrongRebalance = errors.New("index rebalance interval should be days, weeks or months")
+var errIndexWrongWeighting = errors.New("unknown index weighting")
+var errIndexEmpty = errors.New("index components have no common candles")
+var errIndexWrongWeight = errors.New("index component weight and supply should be positive")
+var errIndexZeroWeights = errors.New("index components weights sum should be positive")
+var errIndexWrongPrice = errors.New("index component price should be positive")
+
+type IndexComponent struct {
+	Symbol string
+	Weight float64
+	Supply float64
+}
+
+type IndexDefinition struct {
+	Name              string
+	Components        []IndexComponent
+	Weighting         string
+	RebalanceInterval string
+	BaseTime          uint64
+	BaseValue         float64
+}
+
+// indexPeriod keeps units of every component, that are used from StartTime till next rebalance
+type indexPeriod struct {
+	StartTime uint64
+	Units     []float64
+}
+
+type indexSchedule struct {
+	periods    []indexPeriod
+	validUntil uint64
+}
+
+var indexDefinitions = make(map[string]IndexDefinition)
+var indexSchedules = make(map[string]indexSchedule)
+var indexMu = &sync.RWMutex{}
+
+func (index IndexDefinition) validate() error {
+
+	if len(index.Components) == 0 {
+		return errIndexNoComponents
+	}
+
+	if index.Weighting != IndexWeightEqual && index.Weighting != IndexWeightFixed && index.Weighting != IndexWeightCap {
+		return errIndexWrongWeighting
+	}
+
+	if index.RebalanceInterval != "" {
+
+		letter := candlescommon.IntervalFromStr(index.RebalanceInterval).Letter
+
+		if letter != "d" && letter != "w" && letter != "M" {
+			return errIndexWrongRebalance
+		}
+	}
+
+	for _, component := range index.Components {
+
+		//NaN doesn't pass comparison
+		if index.Weighting == IndexWeightFixed && !(component.Weight > 0 && !math.IsInf(component.Weight, 1)) {
+			return errIndexWrongWeight
+		}
+
+		if index.Weighting == IndexWeightCap && !(component.Supply > 0 && !math.IsInf(component.Supply, 1)) {
+			return errIndexWrongWeight
+		}
+	}
+
+	return nil
+}
+
+// weights returns normalized component weights for prices at rebalance time
+func (index IndexDefinition) weights(prices []float64) ([]float64, error) {
+
+	weights := make([]float64, len(index.Components))
+	sum := 0.0
+
+	for idx, component := range index.Components {
+
+		switch index.Weighting {
+		case IndexWeightEqual:
+			weights[idx] = 1
+		case IndexWeightFixed:
+			weights[idx] = component.Weight
+		case IndexWeightCap:
+			weights[idx] = prices[idx] * component.Supply
+		}
+
+		sum += weights[idx]
+	}
+
+	if !(sum > 0) || math.IsInf(sum, 1) {
+		return nil, errIndexZeroWeights
+	}
+
+	for idx := range weights {
+		weights[idx] /= sum
+	}
+
+	return weights, nil
+}
+
+// rebalanceInterval returns interval of candles that are used for rebalancing
+func (index IndexDefinition) rebalanceInterval() candlescommon.Interval {
+
+	//without rebalancing daily candles are used only to find base prices
+	if index.RebalanceInterval == "" {
+		return candlescommon.IntervalFromStr("1d")
+	}
+
+	return candlescommon.IntervalFromStr(index.RebalanceInterval)
+}
+
+// buildSchedule loads rebalance candles from base time till candle that contains lastOpenTime
+func (index IndexDefinition) buildSchedule(lastOpenTime uint64) (indexSchedule, error) {
+
+	rebalanceInterval := index.rebalanceInterval()
+
+	//the newest loaded candle is dropped if it's closed, so loading starts from the next one
+	timestamp := rebalanceInterval.ExpectedCloseTime(lastOpenTime) + 1
+
+	limit := math.MaxInt32
+
+	if index.BaseTime > 0 && index.BaseTime < timestamp {
+
+		length := rebalanceInterval.Milliseconds()
+
+		//months are at least 28 days long
+		if length == 0 {
+			length = uint64(rebalanceInterval.Duration) * 28 * 24 * 60 * 60 * 1000
+		}
+
+		limit = int((timestamp-index.BaseTime)/length) + 2
+	}
+
+	series := make([][]candlescommon.KLine, 0, len(index.Components))
+
+	for _, component := range index.Components {
+
+		klines, err := GetLastKLinesFromTimestamp(component.Symbol, rebalanceInterval, timestamp, limit)
+
+		if err != nil {
+			return indexSchedule{}, err
+		}
+
+		series = append(series, klines)
+	}
+
+	return index.scheduleFromRows(candlescommon.AlignKlines(series))
+}
+
+// scheduleFromRows calculates units of every period from aligned rebalance candles
+func (index IndexDefinition) scheduleFromRows(rows [][]candlescommon.KLine) (indexSchedule, error) {
+
+	schedule := indexSchedule{periods: make([]indexPeriod, 0)}
+
+	prices := make([]float64, len(index.Components))
+
+	for _, row := range rows {
+
+		if row[0].OpenTime < index.BaseTime {
+			continue
+		}
+
+		value := index.BaseValue
+
+		if len(schedule.periods) > 0 {
+
+			//index value at rebalance time, so series stay continuous
+			value = 0
+
+			for idx, kline := range row {
+				value += schedule.periods[len(schedule.periods)-1].Units[idx] * kline.OpenPrice
+			}
+		}
+
+		for idx, kline := range row {
+
+			if kline.OpenPrice <= 0 {
+				return indexSchedule{}, errIndexWrongPrice
+			}
+
+			prices[idx] = kline.OpenPrice
+		}
+
+		weights, err := index.weights(prices)
+
+		if err != nil {
+			return indexSchedule{}, err
+		}
+
+		units := make([]float64, len(weights))
+
+		for idx := range weights {
+			units[idx] = weights[idx] * value / prices[idx]
+		}
+
+		schedule.periods = append(schedule.periods, indexPeriod{StartTime: row[0].OpenTime, Units: units})
+
+		if index.RebalanceInterval == "" {
+			break
+		}
+	}
+
+	if len(schedule.periods) == 0 {
+		return schedule, errIndexEmpty
+	}
+
+	schedule.validUntil = math.MaxUint64
+
+	if index.RebalanceInterval != "" {
+		schedule.validUntil = index.rebalanceInterval().ExpectedCloseTime(schedule.periods[len(schedule.periods)-1].StartTime) + 1
+	}
+
+	return schedule, nil
+}
+
+func (index IndexDefinition) getSchedule(lastOpenTime uint64) ([]indexPeriod, error) {
+
+	indexMu.RLock()
+	schedule, ok := indexSchedules[index.Name]
+	indexMu.RUnlock()
+
+	if ok && lastOpenTime < schedule.validUntil {
+		return schedule.periods, nil
+	}
+
+	schedule, err := index.buildSchedule(lastOpenTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	indexMu.Lock()
+	indexSchedules[index.Name] = schedule
+	indexMu.Unlock()
+
+	return schedule.periods, nil
+}
+
+// build converts components series to index series
+func (index IndexDefinition) build(series [][]candlescommon.KLine) ([]candlescommon.KLine, error) {
+
+	result := make([]candlescommon.KLine, 0)
+
+	rows := candlescommon.AlignKlines(series)
+
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	periods, err := index.getSchedule(rows[len(rows)-1][0].OpenTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var prevRow []candlescommon.KLine
+
+	for _, row := range rows {
+
+		periodIdx := sort.Search(len(periods), func(i int) bool {
+			return periods[i].StartTime > row[0].OpenTime
+		}) - 1
+
+		//candle before base time
+		if periodIdx < 0 {
+			continue
+		}
+
+		units := periods[periodIdx].Units
+
+		kline := candlescommon.KLine{Symbol: index.Name, OpenTime: row[0].OpenTime, CloseTime: row[0].CloseTime, Closed: true}
+
+		positive := true
+
+		for idx, component := range row {
+
+			positive = positive && component.OpenPrice > 0 && component.ClosePrice > 0 && component.HighPrice > 0 && component.LowPrice > 0
+
+			kline.OpenPrice += units[idx] * component.OpenPrice
+			kline.ClosePrice += units[idx] * component.ClosePrice
+			kline.HighPrice += units[idx] * component.HighPrice
+			kline.LowPrice += units[idx] * component.LowPrice
+
+			//components are expected to have the same quote asset
+			kline.QuoteVolume += component.QuoteVolume
+			kline.TakerBuyQuoteVolume += component.TakerBuyQuoteVolume
+
+			kline.Closed = kline.Closed && component.Closed
+
+			if component.CloseTime < kline.CloseTime {
+				kline.CloseTime = component.CloseTime
+			}
+		}
+
+		kline.HighPrice = math.Max(kline.HighPrice, math.Max(kline.OpenPrice, kline.ClosePrice))
+		kline.LowPrice = math.Min(kline.LowPrice, math.Min(kline.OpenPrice, kline.ClosePrice))
+
+		//NaN doesn't pass comparison, candle without positive prices can't be converted to index units
+		if !positive || !(kline.LowPrice > 0) {
+			continue
+		}
+
+		//volume in index units
+		kline.BaseVolume = kline.QuoteVolume / kline.ClosePrice
+		kline.TakerBuyBaseVolume = kline.TakerBuyQuoteVolume / kline.ClosePrice
+
+		//candle is linked only if every component links to its previous candle, so gap in any component stays a gap
+		linked := true
+
+		for idx, component := range row {
+
+			if component.PrevCloseCandleTimestamp != row[0].PrevCloseCandleTimestamp || (prevRow != nil && component.PrevCloseCandleTimestamp != prevRow[idx].CloseTime) {
+				linked = false
+			}
+		}
+
+		if linked {
+			kline.PrevCloseCandleTimestamp = row[0].PrevCloseCandleTimestamp
+		}
+
+		prevRow = row
+		result = append(result, kline)
+	}
+
+	return result, nil
+}
+
+func (index IndexDefinition) fetch(fetcher func(symbol string) ([]candlescommon.KLine, error)) ([]candlescommon.KLine, error) {
+
+	series := make([][]candlescommon.KLine, 0, len(index.Components))
+
+	for _, component := range index.Components {
+
+		klines, err := fetcher(component.Symbol)
+
+		if err != nil {
+			return nil, err
+		}
+
+		series = append(series, klines)
+	}
+
+	return index.build(series)
+}
+
+func (index IndexDefinition) GetLastKLines(interval candlescommon.Interval, limit int) ([]candlescommon.KLine, error) {
+
+	klines, err := index.fetch(func(symbol string) ([]candlescommon.KLine, error) {
+		return GetLastKLines(symbol, interval, limit)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(klines) == 0 {
+		return nil, errIndexEmpty
+	}
+
+	return klines, nil
+}
+
+func (index IndexDefinition) GetLastKLinesFromTimestamp(interval candlescommon.Interval, timestamp uint64, limit int) ([]candlescommon.KLine, error) {
+
+	return index.fetch(func(symbol string) ([]candlescommon.KLine, error) {
+		return GetLastKLinesFromTimestamp(symbol, interval, timestamp, limit)
+	})
+}
+
+func (index IndexDefinition) GetKLinesInRange(interval candlescommon.Interval, fromTimestamp uint64, endTimestamp uint64, limit int) ([]candlescommon.KLine, error) {
+
+	return index.fetch(func(symbol string) ([]candlescommon.KLine, error) {
+		return GetKLinesInRange(symbol, interval, fromTimestamp, endTimestamp, limit)
+	})
+}
+
+func (index IndexDefinition) GetFirstKLines(interval candlescommon.Interval, limit int) ([]candlescommon.KLine, error) {
+
+	startTime := index.BaseTime
+
+	//index can't start before latest listed component
+	for _, component := range index.Components {
+
+		klines, err := GetFirstKLines(component.Symbol, interval, 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(klines) > 0 && klines[0].OpenTime > startTime {
+			startTime = klines[0].OpenTime
+		}
+	}
+
+	if startTime == 0 {
+		return make([]candlescommon.KLine, 0), nil
+	}
+
+	return index.GetKLinesInRange(interval, startTime-1, math.MaxUint64, limit)
+}
+
+// GetIndexDefinition returns loaded index definition by name
+func GetIndexDefinition(name string) (IndexDefinition, bool) {
+
+	indexMu.RLock()
+	index, ok := indexDefinitions[name]
+	indexMu.RUnlock()
+
+	return index, ok
+}
+
+// SaveIndexDefinition validates and stores index definition in database
+func SaveIndexDefinition(index IndexDefinition) error {
+
+	if index.Weighting == "" {
+		index.Weighting = IndexWeightEqual
+	}
+
+	if index.BaseValue <= 0 {
+		index.BaseValue = 1000
+	}
+
+	err := index.validate()
+
+	if err != nil {
+		return err
+	}
+
+	definition, err := json.Marshal(index)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DatabaseManager.Exec(`INSERT INTO public.tran_indexes(name, definition) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET definition = EXCLUDED.definition;`, index.Name, string(definition))
+
+	if err != nil {
+		return err
+	}
+
+	indexMu.Lock()
+	indexDefinitions[index.Name] = index
+	delete(indexSchedules, index.Name)
+	indexMu.Unlock()
+
+	return nil
+}
+
+// LoadIndexDefinitions reads all stored index definitions
+func LoadIndexDefinitions() error {
+
+	rows, err := database.DatabaseManager.Query(`SELECT definition FROM public.tran_indexes;`)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	indexMu.Lock()
+	defer indexMu.Unlock()
+
+	for rows.Next() {
+
+		var definition string
+
+		err = rows.Scan(&definition)
+
+		if err != nil {
+			return err
+		}
+
+		var index IndexDefinition
+
+		err = json.Unmarshal([]byte(definition), &index)
+
+		if err != nil {
+			return err
+		}
+
+		//definitions saved before validation could have weights that can't be normalized
+		if err = index.validate(); err != nil {
+			log.Println("Wrong index definition: ", index.Name, err.Error())
+			continue
+		}
+
+		indexDefinitions[index.Name] = index
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,197 @@
+package manager
+
+import (
+	"math"
+	"testing"
+
+	"github.com/NERON/tran/candlescommon"
+)
+
+const day = uint64(24 * 60 * 60 * 1000)
+
+// dailyKLines returns chained daily candles opened at days with open and close prices
+func dailyKLines(days []uint64, prices [][2]float64) []candlescommon.KLine {
+
+	klines := make([]candlescommon.KLine, 0, len(days))
+
+	for idx, d := range days {
+
+		kline := candlescommon.KLine{OpenTime: d * day, CloseTime: (d+1)*day - 1, OpenPrice: prices[idx][0], ClosePrice: prices[idx][1], Closed: true}
+		kline.HighPrice = math.Max(kline.OpenPrice, kline.ClosePrice)
+		kline.LowPrice = math.Min(kline.OpenPrice, kline.ClosePrice)
+		kline.QuoteVolume = 100
+
+		if idx > 0 {
+			kline.PrevCloseCandleTimestamp = klines[idx-1].CloseTime
+		}
+
+		klines = append(klines, kline)
+	}
+
+	return klines
+}
+
+func TestIndexWeights(t *testing.T) {
+
+	components := []IndexComponent{{Symbol: "A", Weight: 1, Supply: 1}, {Symbol: "B", Weight: 3, Supply: 3}}
+
+	cases := []struct {
+		weighting string
+		prices    []float64
+		weights   []float64
+		err       error
+	}{
+		{weighting: IndexWeightEqual, prices: []float64{10, 20}, weights: []float64{0.5, 0.5}},
+		{weighting: IndexWeightFixed, prices: []float64{10, 20}, weights: []float64{0.25, 0.75}},
+		{weighting: IndexWeightCap, prices: []float64{10, 20}, weights: []float64{1.0 / 7, 6.0 / 7}},
+		{weighting: IndexWeightCap, prices: []float64{0, 0}, err: errIndexZeroWeights},
+	}
+
+	for _, c := range cases {
+
+		index := IndexDefinition{Components: components, Weighting: c.weighting}
+
+		weights, err := index.weights(c.prices)
+
+		if err != c.err {
+			t.Errorf("%s: error %v, expected %v", c.weighting, err, c.err)
+			continue
+		}
+
+		for idx := range c.weights {
+
+			if math.Abs(weights[idx]-c.weights[idx]) > 1e-9 {
+				t.Errorf("%s: weights %v, expected %v", c.weighting, weights, c.weights)
+				break
+			}
+		}
+	}
+}
+
+func TestIndexScheduleRebalance(t *testing.T) {
+
+	rows := candlescommon.AlignKlines([][]candlescommon.KLine{
+		dailyKLines([]uint64{0, 1, 2}, [][2]float64{{5, 5}, {10, 20}, {20, 20}}),
+		dailyKLines([]uint64{0, 1, 2}, [][2]float64{{5, 5}, {20, 20}, {20, 10}}),
+	})
+
+	index := IndexDefinition{Components: []IndexComponent{{Symbol: "A"}, {Symbol: "B"}}, Weighting: IndexWeightEqual, RebalanceInterval: "1d", BaseTime: day, BaseValue: 1000}
+
+	schedule, err := index.scheduleFromRows(rows)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the second period starts from index value 50*20+25*20 at rebalance time
+	expected := []indexPeriod{{StartTime: day, Units: []float64{50, 25}}, {StartTime: 2 * day, Units: []float64{37.5, 37.5}}}
+
+	if len(schedule.periods) != len(expected) || schedule.validUntil != 3*day {
+		t.Fatalf("schedule %+v", schedule)
+	}
+
+	for idx, period := range schedule.periods {
+
+		if period.StartTime != expected[idx].StartTime || period.Units[0] != expected[idx].Units[0] || period.Units[1] != expected[idx].Units[1] {
+			t.Errorf("period %d is %+v, expected %+v", idx, period, expected[idx])
+		}
+	}
+
+	//without rebalancing only base prices are used
+	index.RebalanceInterval = ""
+
+	schedule, err = index.scheduleFromRows(rows)
+
+	if err != nil || len(schedule.periods) != 1 || schedule.validUntil != math.MaxUint64 {
+		t.Errorf("schedule without rebalancing %+v, %v", schedule, err)
+	}
+
+	index.BaseTime = 3 * day
+
+	if _, err = index.scheduleFromRows(rows); err != errIndexEmpty {
+		t.Errorf("schedule after the last candle returned %v", err)
+	}
+}
+
+func TestIndexScheduleSupply(t *testing.T) {
+
+	rows := candlescommon.AlignKlines([][]candlescommon.KLine{
+		dailyKLines([]uint64{0}, [][2]float64{{10, 10}}),
+		dailyKLines([]uint64{0}, [][2]float64{{20, 20}}),
+	})
+
+	index := IndexDefinition{Components: []IndexComponent{{Symbol: "A", Supply: 1}, {Symbol: "B", Supply: 3}}, Weighting: IndexWeightCap, BaseValue: 1000}
+
+	schedule, err := index.scheduleFromRows(rows)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//units of cap weighted index are proportional to supply
+	units := schedule.periods[0].Units
+
+	if math.Abs(units[1]/units[0]-3) > 1e-9 || math.Abs(units[0]*10+units[1]*20-1000) > 1e-9 {
+		t.Errorf("cap weighted units %v", units)
+	}
+
+	rows[0][1].OpenPrice = 0
+
+	if _, err = index.scheduleFromRows(rows); err != errIndexWrongPrice {
+		t.Errorf("schedule with zero price returned %v", err)
+	}
+}
+
+func TestIndexBuild(t *testing.T) {
+
+	index := IndexDefinition{Name: "TESTINDEX", Components: []IndexComponent{{Symbol: "A"}, {Symbol: "B"}}, Weighting: IndexWeightEqual}
+
+	indexMu.Lock()
+	indexSchedules[index.Name] = indexSchedule{periods: []indexPeriod{{StartTime: day, Units: []float64{1, 2}}}, validUntil: math.MaxUint64}
+	indexMu.Unlock()
+
+	defer func() {
+		indexMu.Lock()
+		delete(indexSchedules, index.Name)
+		indexMu.Unlock()
+	}()
+
+	first := dailyKLines([]uint64{0, 1, 2, 3, 4, 5}, [][2]float64{{1, 1}, {10, 20}, {20, 10}, {0, 0}, {10, 10}, {10, 10}})
+	second := dailyKLines([]uint64{0, 1, 2, 3, 5}, [][2]float64{{1, 1}, {20, 20}, {20, 10}, {10, 10}, {10, 10}})
+
+	klines, err := index.build([][]candlescommon.KLine{first, second})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the first day is before schedule, day with zero price and day missed in second series are skipped
+	expected := []struct {
+		openTime    uint64
+		open, close float64
+		linked      bool
+	}{
+		{day, 50, 60, false},
+		{2 * day, 60, 30, true},
+		{5 * day, 30, 30, false},
+	}
+
+	if len(klines) != len(expected) {
+		t.Fatalf("index klines %+v", klines)
+	}
+
+	for idx, kline := range klines {
+
+		e := expected[idx]
+
+		linked := idx > 0 && kline.PrevCloseCandleTimestamp == klines[idx-1].CloseTime
+
+		if kline.OpenTime != e.openTime || kline.OpenPrice != e.open || kline.ClosePrice != e.close || linked != e.linked || (!linked && idx > 0 && kline.PrevCloseCandleTimestamp != 0) {
+			t.Errorf("kline %d is %+v, expected %+v", idx, kline, e)
+		}
+
+		if kline.BaseVolume != kline.QuoteVolume/kline.ClosePrice || kline.QuoteVolume != 200 {
+			t.Errorf("kline %d volumes %f %f", idx, kline.BaseVolume, kline.QuoteVolume)
+		}
+	}
+}
@@ -104,6 +104,8 @@ func FillDatabaseToLatestValues(symbol string, interval candlescommon.Interval)
 			klines = candlescommon.HoursGroupKlineDesc(klines, uint64(interval.Duration), false, false)
 		} else if interval.Letter == "m" && interval.Duration != timeframe {
 			klines = candlescommon.MinutesGroupKlineDesc(klines, uint64(interval.Duration), false, false)
+		} else if interval.Letter == "s" && interval.Duration != timeframe {
+			klines = candlescommon.SecondsGroupKlineDesc(klines, uint64(interval.Duration), false, false)
 		}
 
 		SaveCandles(klines, interval)
@@ -122,6 +124,8 @@ func FillDatabaseToLatestValues(symbol string, interval candlescommon.Interval)
 				loadedKlines = candlescommon.HoursGroupKlineDesc(loadedKlines, uint64(interval.Duration), false, false)
 			} else if interval.Letter == "m" && interval.Duration != timeframe {
 				loadedKlines = candlescommon.MinutesGroupKlineDesc(loadedKlines, uint64(interval.Duration), false, false)
+			} else if interval.Letter == "s" && interval.Duration != timeframe {
+				loadedKlines = candlescommon.SecondsGroupKlineDesc(loadedKlines, uint64(interval.Duration), false, false)
 			}
 
 			for i := 0; i < len(loadedKlines)/2; i++ {
@@ -174,6 +178,8 @@ func FillDatabaseWithPrevValues(symbol string, interval candlescommon.Interval,
 			loadedKlines = candlescommon.HoursGroupKlineDesc(loadedKlines, uint64(interval.Duration), true, false)
 		} else if interval.Letter == "m" && interval.Duration != timeframe {
 			loadedKlines = candlescommon.MinutesGroupKlineDesc(loadedKlines, uint64(interval.Duration), true, false)
+		} else if interval.Letter == "s" && interval.Duration != timeframe {
+			loadedKlines = candlescommon.SecondsGroupKlineDesc(loadedKlines, uint64(interval.Duration), true, false)
 		}
 
 		SaveCandles(loadedKlines, interval)
@@ -93,12 +93,22 @@ func convertKlinesToNewTimestamp(klines []candlescommon.KLine, interval candlesc
 		klines = candlescommon.HoursGroupKlineDesc(klines, uint64(interval.Duration), true, false)
 	} else if interval.Letter == "m" {
 		klines = candlescommon.MinutesGroupKlineDesc(klines, uint64(interval.Duration), true, false)
+	} else if interval.Letter == "s" {
+		klines = candlescommon.SecondsGroupKlineDesc(klines, uint64(interval.Duration), true, false)
 	}
 
 	return klines
 }
 func GetFirstKLines(symbol string, interval candlescommon.Interval, limit int) ([]candlescommon.KLine, error) {
 
+	if synthetic, ok := GetSyntheticSymbol(symbol); ok {
+		return synthetic.GetFirstKLines(interval, limit)
+	}
+
+	if index, ok := GetIndexDefinition(symbol); ok {
+		return index.GetFirstKLines(interval, limit)
+	}
+
 	//get interval for loading data
 	databaseInterval := GetOptimalDatabaseTimeframe(interval)
 
@@ -133,6 +143,8 @@ func GetFirstKLines(symbol string, interval candlescommon.Interval, limit int) (
 		fetchedData = candlescommon.HoursGroupKlineDesc(fetchedData, uint64(interval.Duration), false, true)
 	} else if interval.Letter == "m" {
 		fetchedData = candlescommon.MinutesGroupKlineDesc(fetchedData, uint64(interval.Duration), false, true)
+	} else if interval.Letter == "s" {
+		fetchedData = candlescommon.SecondsGroupKlineDesc(fetchedData, uint64(interval.Duration), false, true)
 	}
 
 	for i := 0; i < len(fetchedData)/2; i++ {
@@ -144,6 +156,14 @@ func GetFirstKLines(symbol string, interval candlescommon.Interval, limit int) (
 }
 func GetKLinesInRange(symbol string, interval candlescommon.Interval, fromTimestamp uint64, endTimestamp uint64, limit int) ([]candlescommon.KLine, error) {
 
+	if synthetic, ok := GetSyntheticSymbol(symbol); ok {
+		return synthetic.GetKLinesInRange(interval, fromTimestamp, endTimestamp, limit)
+	}
+
+	if index, ok := GetIndexDefinition(symbol); ok {
+		return index.GetKLinesInRange(interval, fromTimestamp, endTimestamp, limit)
+	}
+
 	//get interval for loading data
 	databaseInterval := GetOptimalDatabaseTimeframe(interval)
 
@@ -183,6 +203,8 @@ func GetKLinesInRange(symbol string, interval candlescommon.Interval, fromTimest
 			fetchedData = candlescommon.HoursGroupKlineDesc(fetchedData, uint64(interval.Duration), false, true)
 		} else if interval.Letter == "m" {
 			fetchedData = candlescommon.MinutesGroupKlineDesc(fetchedData, uint64(interval.Duration), false, true)
+		} else if interval.Letter == "s" {
+			fetchedData = candlescommon.SecondsGroupKlineDesc(fetchedData, uint64(interval.Duration), false, true)
 		}
 
 		if len(fetchedData) == 0 {
@@ -218,6 +240,14 @@ func GetKLinesInRange(symbol string, interval candlescommon.Interval, fromTimest
 }
 func GetLastKLines(symbol string, interval candlescommon.Interval, limit int) ([]candlescommon.KLine, error) {
 
+	if synthetic, ok := GetSyntheticSymbol(symbol); ok {
+		return synthetic.GetLastKLines(interval, limit)
+	}
+
+	if index, ok := GetIndexDefinition(symbol); ok {
+		return index.GetLastKLines(interval, limit)
+	}
+
 	databaseInterval := GetOptimalDatabaseTimeframe(interval)
 
 	var loadInterval = uint(0)
@@ -334,6 +364,14 @@ func GetLastKLines(symbol string, interval candlescommon.Interval, limit int) ([
 }
 func GetLastKLinesFromTimestamp(symbol string, interval candlescommon.Interval, timestamp uint64, limit int) ([]candlescommon.KLine, error) {
 
+	if synthetic, ok := GetSyntheticSymbol(symbol); ok {
+		return synthetic.GetLastKLinesFromTimestamp(interval, timestamp, limit)
+	}
+
+	if index, ok := GetIndexDefinition(symbol); ok {
+		return index.GetLastKLinesFromTimestamp(interval, timestamp, limit)
+	}
+
 	databaseInterval := GetOptimalDatabaseTimeframe(interval)
 
 	var loadInterval = uint(0)
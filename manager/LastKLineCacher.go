@@ -27,7 +27,10 @@ type symbolKlines struct {
 	mu *sync.RWMutex
 }
 
-func (s *symbolKlines) SetActiveKline(kline candlescommon.KLine) {
+// SetActiveKline returns true if previous kline was closed and moved to archive
+func (s *symbolKlines) SetActiveKline(kline candlescommon.KLine) bool {
+
+	archived := false
 
 	//try to lock access to structure
 	s.mu.Lock()
@@ -64,6 +67,7 @@ func (s *symbolKlines) SetActiveKline(kline candlescommon.KLine) {
 
 			//append kline to archive
 			s.archivedKlines = append(s.archivedKlines, s.activeKline)
+			archived = true
 
 			if len(s.archivedKlines) > int(s.archiveLength) {
 				s.archivedKlines = s.archivedKlines[1:]
@@ -80,6 +84,8 @@ func (s *symbolKlines) SetActiveKline(kline candlescommon.KLine) {
 
 	//unlock resource
 	s.mu.Unlock()
+
+	return archived
 }
 func (s *symbolKlines) GetData() []candlescommon.KLine {
 
@@ -249,9 +255,33 @@ func newSymbolKLines(symbol string, timeframe string, archiveLength uint) *symbo
 
 }
 
+// KLineCloseListener is called from websocket goroutine when kline is closed, it shouldn't block
+type KLineCloseListener func(symbol string, timeframe string)
+
 type LastKlinesCaches struct {
 	symbols map[string]map[string]*symbolKlines
 	ws      *providers.BinanceWebsocketProvider
+
+	listeners   []KLineCloseListener
+	listenersMu *sync.RWMutex
+}
+
+// Subscribe adds listener of closed klines of all cached symbols
+func (s *LastKlinesCaches) Subscribe(listener KLineCloseListener) {
+
+	s.listenersMu.Lock()
+	s.listeners = append(s.listeners, listener)
+	s.listenersMu.Unlock()
+}
+
+func (s *LastKlinesCaches) notifyClosed(symbol string, timeframe string) {
+
+	s.listenersMu.RLock()
+	defer s.listenersMu.RUnlock()
+
+	for _, listener := range s.listeners {
+		listener(symbol, timeframe)
+	}
 }
 
 func toFloat(value string) float64 {
@@ -269,6 +299,26 @@ func (s *LastKlinesCaches) GetLatestKLines(symbol string, interval candlescommon
 
 	klineCacher, ok := s.symbols[fmt.Sprintf("1%s", interval.Letter)][symbol]
 
+	//synthetic symbol can be built from cached legs
+	if synthetic, isSynthetic := GetSyntheticSymbol(symbol); !ok && isSynthetic {
+
+		first, ok := s.GetLatestKLines(synthetic.First, interval)
+
+		if !ok {
+			return nil, false
+		}
+
+		second, ok := s.GetLatestKLines(synthetic.Second, interval)
+
+		if !ok {
+			return nil, false
+		}
+
+		klineData := candlescommon.CombineKlines(synthetic.Name, first, second, synthetic.Operation)
+
+		return klineData, len(klineData) > 0
+	}
+
 	if !ok {
 		return nil, false
 	}
@@ -295,21 +345,31 @@ func (s *LastKlinesCaches) GetLatestKLines(symbol string, interval candlescommon
 
 }
 
+// cachedArchiveLengths is how many 1m and 1h candles are cached, other intervals of the letter are grouped from them
+var cachedArchiveLengths = map[string]uint{"m": 3 * 1440, "h": 50}
+
+// IsCachedInterval checks that GetLatestKLines can build at least one candle of interval from cached candles
+func IsCachedInterval(interval candlescommon.Interval) bool {
+
+	length, ok := cachedArchiveLengths[interval.Letter]
+
+	return ok && interval.Duration > 0 && interval.Duration <= length
+}
+
 func NewLastKlinesCacher(symbols []string) (*LastKlinesCaches, error) {
 
 	klines := &LastKlinesCaches{
-		symbols: make(map[string]map[string]*symbolKlines),
+		symbols:     make(map[string]map[string]*symbolKlines),
+		listenersMu: &sync.RWMutex{},
 	}
 
-	archiveLengths := []uint{3 * 1440, 50}
-
-	for idx, interval := range []string{"1m", "1h"} {
+	for _, interval := range []string{"1m", "1h"} {
 
 		klines.symbols[interval] = make(map[string]*symbolKlines)
 
 		for _, symbol := range symbols {
 
-			klines.symbols[interval][symbol] = newSymbolKLines(symbol, interval, archiveLengths[idx])
+			klines.symbols[interval][symbol] = newSymbolKLines(symbol, interval, cachedArchiveLengths[interval[1:]])
 		}
 
 	}
@@ -345,7 +405,9 @@ func NewLastKlinesCacher(symbols []string) (*LastKlinesCaches, error) {
 			TakerBuyBaseVolume:  toFloat(wsKline.ActiveBuyVolume),
 		}
 
-		klineCacher.SetActiveKline(kline)
+		if klineCacher.SetActiveKline(kline) {
+			klines.notifyClosed(wsKline.Symbol, wsKline.Interval)
+		}
 
 	})
 
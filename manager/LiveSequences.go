@@ -0,0 +1,374 @@
+package manager
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/NERON/tran/candlescommon"
+	"github.com/NERON/tran/database"
+	"github.com/NERON/tran/indicators"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultLiveSnapshotInterval is how often live sequences are saved to database
+const DefaultLiveSnapshotInterval = 15 * time.Minute
+
+var LiveSequencer *LiveSequences
+
+var errNotCachedKLines = errors.New("klines are not cached")
+var errNoSavedSequences = errors.New("saved sequences not found")
+var errWrongLiveInterval = errors.New("live sequence interval should be minutes up to 3 days or hours up to 50 hours, like 1h or 72m")
+
+// LiveSequenceConfig is combination of symbol, interval, central RSI and options kept live
+type LiveSequenceConfig struct {
+	Symbol     string
+	Interval   string
+	CentralRSI uint
+	Options    SequenceOptions
+}
+
+type liveSequence struct {
+	symbol      string
+	intervalStr string
+	interval    candlescommon.Interval
+	centralRSI  uint
+	options     SequenceOptions
+
+	//tracker counted candles up to counted candle
+	tracker *SequenceTracker
+	counted uint64
+
+	//the last closed candle, tracker gets it with the next closed candle because it can still confirm lows before it.
+	//Queries count it on copy of tracker
+	pending candlescommon.KLine
+
+	saved        uint64
+	lastSnapshot time.Time
+
+	mu *sync.RWMutex
+}
+
+// LiveSequences keeps sequences of tracked symbols and intervals counted up to the last closed candle of KLineCacher
+type LiveSequences struct {
+	cacher           *LastKlinesCaches
+	snapshotInterval time.Duration
+
+	sequences map[string]*liveSequence
+	mu        *sync.RWMutex
+
+	updates chan struct{}
+}
+
+func liveSequenceKey(symbol string, interval string, centralRSI uint, options SequenceOptions) string {
+	return fmt.Sprintf("%s_%s_%d_%s", symbol, interval, centralRSI, options.Key())
+}
+
+func NewLiveSequences(cacher *LastKlinesCaches, snapshotInterval time.Duration) *LiveSequences {
+
+	live := &LiveSequences{
+		cacher:           cacher,
+		snapshotInterval: snapshotInterval,
+		sequences:        make(map[string]*liveSequence),
+		mu:               &sync.RWMutex{},
+		updates:          make(chan struct{}, 1),
+	}
+
+	//intervals are grouped from cached klines, so any closed kline can close tracked candle
+	cacher.Subscribe(func(symbol string, timeframe string) {
+		live.notify()
+	})
+
+	go live.run()
+
+	return live
+}
+
+func (l *LiveSequences) notify() {
+
+	select {
+	case l.updates <- struct{}{}:
+	default:
+	}
+}
+
+// Track starts keeping sequences of symbol, interval and options live, state is loaded on the next update
+func (l *LiveSequences) Track(symbol string, intervalStr string, centralRSI uint, options SequenceOptions) {
+
+	l.mu.Lock()
+
+	key := liveSequenceKey(symbol, intervalStr, centralRSI, options)
+
+	if _, ok := l.sequences[key]; !ok {
+		l.sequences[key] = &liveSequence{symbol: symbol, intervalStr: intervalStr, interval: candlescommon.IntervalFromStr(intervalStr), centralRSI: centralRSI, options: options, mu: &sync.RWMutex{}}
+	}
+
+	l.mu.Unlock()
+
+	l.notify()
+}
+
+// LoadLiveSequenceConfigs reads tracked combinations from tran_live_sequences, wrong rows are skipped
+func LoadLiveSequenceConfigs() ([]LiveSequenceConfig, error) {
+
+	rows, err := database.DatabaseManager.Query(`SELECT symbol, "interval", "centralRSI", options FROM public.tran_live_sequences ORDER BY symbol, "interval", "centralRSI", options;`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	configs := make([]LiveSequenceConfig, 0)
+
+	for rows.Next() {
+
+		var config LiveSequenceConfig
+		var options string
+
+		err = rows.Scan(&config.Symbol, &config.Interval, &config.CentralRSI, &options)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if options != "" {
+			err = json.Unmarshal([]byte(options), &config.Options)
+		}
+
+		if err == nil {
+			err = config.validate()
+		}
+
+		if err != nil {
+			log.Println("Wrong live sequence: ", config.Symbol, config.Interval, config.CentralRSI, options, err.Error())
+			continue
+		}
+
+		configs = append(configs, config)
+	}
+
+	return configs, rows.Err()
+}
+
+func (config LiveSequenceConfig) validate() error {
+
+	//live candles are built only from cached 1m and 1h candles
+	if len(config.Interval) < 2 || !IsCachedInterval(candlescommon.IntervalFromStr(config.Interval)) {
+		return errWrongLiveInterval
+	}
+
+	return config.Options.Validate()
+}
+
+// Get returns copy of sequences and RSI counted up to the last closed candle
+func (l *LiveSequences) Get(symbol string, intervalStr string, centralRSI uint, options SequenceOptions) (*list.List, *indicators.RSIMultiplePeriods, bool) {
+
+	l.mu.RLock()
+	sequence, ok := l.sequences[liveSequenceKey(symbol, intervalStr, centralRSI, options)]
+	l.mu.RUnlock()
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	sequence.mu.RLock()
+	defer sequence.mu.RUnlock()
+
+	if sequence.tracker == nil {
+		return nil, nil, false
+	}
+
+	tracker := sequence.tracker.Clone()
+
+	if sequence.pending.OpenTime > 0 {
+		tracker.Add(sequence.pending)
+	}
+
+	return tracker.List(), tracker.RSI, true
+}
+
+func (l *LiveSequences) run() {
+
+	for range l.updates {
+
+		l.mu.RLock()
+
+		sequences := make([]*liveSequence, 0, len(l.sequences))
+
+		for _, sequence := range l.sequences {
+			sequences = append(sequences, sequence)
+		}
+
+		l.mu.RUnlock()
+
+		for _, sequence := range sequences {
+
+			err := sequence.update(l.cacher)
+
+			if err != nil {
+				log.Println("Live sequences update error: ", sequence.symbol, sequence.intervalStr, err.Error())
+				continue
+			}
+
+			if time.Since(sequence.lastSnapshot) >= l.snapshotInterval {
+
+				err = sequence.snapshot()
+
+				if err != nil {
+					log.Println("Live sequences snapshot error: ", sequence.symbol, sequence.intervalStr, err.Error())
+				}
+			}
+		}
+	}
+}
+
+func (s *liveSequence) update(cacher *LastKlinesCaches) error {
+
+	candles, ok := cacher.GetLatestKLines(s.symbol, s.interval)
+
+	if !ok {
+		return errNotCachedKLines
+	}
+
+	//unclosed candle can't be used for count
+	if len(candles) > 0 && !candles[len(candles)-1].Closed {
+		candles = candles[:len(candles)-1]
+	}
+
+	if s.tracker == nil {
+		return s.load(candles)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.advance(candles)
+
+	//state is loaded again from database on the next update
+	if err != nil {
+		s.tracker = nil
+	}
+
+	return err
+}
+
+func (s *liveSequence) load(candles []candlescommon.KLine) error {
+
+	sequences, lastUpdate, rsip, err := GetSequncesWithUpdate(s.symbol, s.interval, s.centralRSI, s.options, math.MaxInt64)
+
+	if err != nil {
+		return err
+	}
+
+	//database is already up to date
+	if rsip == nil {
+
+		sequences, lastUpdate, rsip, err = GetPeriodsFromDatabase(s.symbol, s.intervalStr, s.centralRSI, s.options, math.MaxInt64)
+
+		if err != nil {
+			return err
+		}
+
+		if rsip == nil {
+			return errNoSavedSequences
+		}
+	}
+
+	tracker, err := s.options.NewTracker(rsip, float64(s.centralRSI))
+
+	if err != nil {
+		return err
+	}
+
+	tracker.Continue(sequences, lastUpdate)
+
+	//candles that were counted before only pass to detector
+	idx := sort.Search(len(candles), func(i int) bool {
+		return candles[i].OpenTime >= lastUpdate
+	})
+
+	if idx == len(candles) || candles[idx].OpenTime != lastUpdate {
+		return errWrongSavedTimestamp
+	}
+
+	tracker.PrimeDetector(candles[:idx+1])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tracker = tracker
+	s.counted = lastUpdate
+	s.pending = candlescommon.KLine{}
+	s.saved = lastUpdate
+	s.lastSnapshot = time.Now()
+
+	return s.advance(candles[idx:])
+}
+
+// advance counts closed candles after the last known candle, candles should contain the last known candle
+func (s *liveSequence) advance(candles []candlescommon.KLine) error {
+
+	lastKnown := s.counted
+
+	if s.pending.OpenTime > 0 {
+		lastKnown = s.pending.OpenTime
+	}
+
+	idx := sort.Search(len(candles), func(i int) bool {
+		return candles[i].OpenTime >= lastKnown
+	})
+
+	if idx == len(candles) || candles[idx].OpenTime != lastKnown {
+		return errWrongSavedTimestamp
+	}
+
+	for _, candle := range candles[idx+1:] {
+
+		if s.pending.OpenTime > 0 {
+
+			s.tracker.Add(s.pending)
+			s.counted = s.pending.OpenTime
+		}
+
+		s.pending = candle
+	}
+
+	return nil
+}
+
+// snapshot saves sequences like GetSequncesWithUpdate, pending candle only confirms lows before it
+func (s *liveSequence) snapshot() error {
+
+	s.mu.RLock()
+
+	if s.tracker == nil || s.pending.OpenTime == 0 || s.counted <= s.saved {
+		s.mu.RUnlock()
+		return nil
+	}
+
+	tracker := s.tracker.Clone()
+	counted := s.counted
+	pending := s.pending
+
+	s.mu.RUnlock()
+
+	tracker.Confirm(pending)
+
+	err := SaveSequences(s.symbol, s.intervalStr, s.centralRSI, s.options, tracker.List(), counted, tracker.RSI)
+
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.saved = counted
+	s.lastSnapshot = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
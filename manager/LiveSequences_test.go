@@ -0,0 +1,32 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestLiveSequenceConfigInterval(t *testing.T) {
+
+	cases := []struct {
+		interval string
+		err      error
+	}{
+		{interval: "1m"},
+		{interval: "72m"},
+		{interval: "4320m"},
+		{interval: "12h"},
+		{interval: "50h"},
+		{interval: "4321m", err: errWrongLiveInterval},
+		{interval: "51h", err: errWrongLiveInterval},
+		{interval: "1d", err: errWrongLiveInterval},
+		{interval: "1w", err: errWrongLiveInterval},
+		{interval: "0h", err: errWrongLiveInterval},
+		{interval: "h", err: errWrongLiveInterval},
+	}
+
+	for _, c := range cases {
+
+		if err := (LiveSequenceConfig{Symbol: "ETHUSDT", Interval: c.interval, CentralRSI: DefaultCentralRSI}).validate(); err != c.err {
+			t.Errorf("interval %s returned %v, expected %v", c.interval, err, c.err)
+		}
+	}
+}
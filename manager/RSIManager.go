@@ -28,11 +28,11 @@ func GenerateMapOfPeriods(symbol string, interval candlescommon.Interval, endTim
 	lastHandledCandle := uint64(0)
 
 	lowReverse := indicators.NewRSILowReverseIndicator()
-	RSI := indicators.NewRSIMultiplePeriods(250)
+	RSI := indicators.NewRSIMultiplePeriodsWithSettings(indicators.DefaultMaxPeriod, indicators.RSISmoothingWilder, indicators.PriceSourceLow)
 
 	currentPeriods := make(map[int]map[int]PeriodInfo)
 
-	centralRSIs := []int{15}
+	centralRSIs := []int{int(centralRSI)}
 
 	maxPeriod := 0
 	tmpt := uint64(0)
@@ -127,7 +127,7 @@ func GenerateMapOfPeriods(symbol string, interval candlescommon.Interval, endTim
 			}
 
 			lastHandledCandle = candle.OpenTime
-			RSI.AddPoint(candle.LowPrice)
+			RSI.AddCandle(candle)
 
 		}
 
@@ -2,12 +2,9 @@ package manager
 
 import (
 	"container/list"
-	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/NERON/tran/candlescommon"
-	"github.com/NERON/tran/database"
 	"github.com/NERON/tran/indicators"
 	"log"
 	"math"
@@ -16,6 +13,10 @@ import (
 
 var errWrongSavedTimestamp = errors.New("candle that was used previously are missed")
 
+// DefaultCentralRSI is central RSI of sequences when request doesn't set it,
+// sequences saved before central RSI was stored have this level
+const DefaultCentralRSI = 15
+
 type SequenceValue struct {
 	Sequence        int
 	LowCentralPrice bool
@@ -26,59 +27,29 @@ type SequenceValue struct {
 	Lower           float64
 	Down            float64
 	Count           uint
-}
-
-func GetPeriodsFromDatabase(symbol string, interval string, timestamp int64) (*list.List, uint64, *indicators.RSIMultiplePeriods, error) {
-
-	var listJSon string
-	var RSIJSon string
-	var lastUpdate uint64
-
-	err := database.DatabaseManager.QueryRow(`SELECT  list,"lastUpdate","lastRSI" FROM public."tran_bestPeriodsList" WHERE symbol=$1 AND interval=$2 AND "lastUpdate" < $3 ORDER BY "lastUpdate" DESC LIMIT 1;`, symbol, interval, timestamp).Scan(&listJSon, &lastUpdate, &RSIJSon)
-
-	if err != nil && err != sql.ErrNoRows {
-		return nil, 0, nil, err
-	}
 
-	if err == sql.ErrNoRows {
-		return list.New(), 0, nil, nil
-	}
-
-	var sequenceArray []SequenceValue
-
-	err = json.Unmarshal([]byte(listJSon), &sequenceArray)
-
-	if err != nil {
-		return nil, 0, nil, err
-	}
+	//upper border of the period zone
+	Up float64 `json:",omitempty"`
 
-	sequenceList := list.New()
+	//RSI of the longest period hasn't converged when sequence was found
+	InsufficientHistory bool `json:",omitempty"`
 
-	for _, val := range sequenceArray {
-		sequenceList.PushBack(val)
-	}
-
-	var RSI indicators.RSIMultiplePeriods
-
-	err = json.Unmarshal([]byte(RSIJSon), &RSI)
-
-	if err != nil {
-		return nil, 0, nil, err
-	}
-
-	return sequenceList, lastUpdate, &RSI, nil
+	//pivot was outside of zones of all periods, such pivot is reported, but isn't counted
+	LadderPosition string `json:",omitempty"`
 }
 
-func GetSequncesWithUpdate(symbol string, interval candlescommon.Interval, timestamp int64) (*list.List, uint64, *indicators.RSIMultiplePeriods, error) {
+func GetSequncesWithUpdate(symbol string, interval candlescommon.Interval, centralRSI uint, options SequenceOptions, timestamp int64) (*list.List, uint64, *indicators.RSIMultiplePeriods, error) {
 
 	prevCandle := candlescommon.KLine{}
 
+	//the first candles of newer batch, they confirm lows of the last candles of older batch
+	nextCandles := make([]candlescommon.KLine, 0)
+
 	done := false
 	newEndTimestamp := uint64(0)
 
-	lastSavedSequences, lastKlineTimestamp, _, err := GetPeriodsFromDatabase(symbol, fmt.Sprintf("%d%s", interval.Duration, interval.Letter), timestamp)
+	lastSavedSequences, lastKlineTimestamp, _, err := GetPeriodsFromDatabase(symbol, fmt.Sprintf("%d%s", interval.Duration, interval.Letter), centralRSI, options, timestamp)
 
-	centralRSI := 15
 	if err != nil {
 		return nil, 0, nil, err
 	}
@@ -155,142 +126,74 @@ func GetSequncesWithUpdate(symbol string, interval candlescommon.Interval, times
 			}
 		}
 
+		rsiP := indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod)
+
 		//get old candles data
-		candlesOld, err := GetLastKLinesFromTimestamp(symbol, interval, candles[0].OpenTime, 500)
+		candlesOld, err := GetRSIWarmUpKLines(symbol, interval, candles[0].OpenTime, rsiP)
 
 		//check for errors
 		if err != nil {
 			return nil, 0, nil, err
 		}
 
-		//initialize Low reverse counter for calculating potential low points
-		reverseLow := indicators.NewRSILowReverseIndicator()
+		//tracker gets old candles for RSI and detector, lows among them are counted by older requests
+		tracker, err := options.NewTracker(rsiP, float64(centralRSI))
 
-		//if old candles is present insert last candle for checking is first candle in position
-		if len(candlesOld) > 0 {
-			reverseLow.AddPoint(candlesOld[len(candlesOld)-1].LowPrice, 0)
-		}
-
-		//check if previous candle present, if true we should append it, for calculating value for last candle
-		if prevCandle.OpenTime > 0 {
-			candles = append(candles, prevCandle)
+		if err != nil {
+			return nil, 0, nil, err
 		}
 
-		//generate map with indexes of all potential lows
-		lowsMap := indicators.GenerateMapLows(reverseLow, candles)
-
-		//remove previously inserted last candle
-		if prevCandle.OpenTime > 0 {
-			candles = candles[:len(candles)-1]
-
-		} else {
+		//lows of the last saved candles can be confirmed only by new candles, the last saved pivot is the front of the stack
+		if done {
 
-			//remove pre-last element because it should be only used for pre-counts
-			delete(lowsMap, len(candles)-1)
+			lastPivot := uint64(0)
 
-			//remove element
-			candles = candles[:len(candles)-1]
-
-			//set new timestamp
-			if len(candles) > 0 {
-
-				newEndTimestamp = candles[len(candles)-1].OpenTime
+			if front := lastSavedSequences.Front(); front != nil {
+				lastPivot = front.Value.(SequenceValue).Timestamp
 			}
 
-		}
-
-		//start calculating
-		rsiP := indicators.NewRSIMultiplePeriods(250)
+			tracker.Resume(candlesOld, lastPivot)
 
-		//first insert all old candles
-		for _, candleOld := range candlesOld {
-
-			rsiP.AddPoint(candleOld.ClosePrice)
+		} else {
 
+			tracker.Prime(candlesOld)
 		}
 
-		bestSequenceList := list.New()
-
-		for idx, candle := range candles {
-
-			_, ok := lowsMap[idx]
-
-			if ok {
-
-				bestPeriod, _, centralPrice := rsiP.GetBestPeriod(candle.LowPrice, float64(centralRSI))
-
-				periods := make([]int, 0)
+		batch := candles
 
-				up, down, _ := rsiP.GetIntervalForPeriod(bestPeriod, float64(centralRSI))
-
-				if bestPeriod > 2 || (bestPeriod == 2 && candle.LowPrice <= up) {
-
-					periods = append(periods, bestPeriod)
-
-					for _, period := range periods {
+		//check if previous candle present, if true the first candles of newer batch only confirm lows of the last candles
+		if prevCandle.OpenTime > 0 {
 
-						sequence := SequenceValue{LowCentralPrice: true, Sequence: period, CentralPrice: centralPrice, Fictive: bestPeriod != period, Timestamp: candle.OpenTime, Central: centralPrice, Lower: candle.LowPrice, Down: down, Count: 1}
+			for _, candle := range candles {
+				tracker.Add(candle)
+			}
 
-						if sequence.Fictive {
-							sequence.Count -= 1
-						}
-						for e := bestSequenceList.Front(); e != nil && e.Value.(SequenceValue).Sequence <= period; e = bestSequenceList.Front() {
+			tracker.Confirm(nextCandles...)
 
-							if sequence.Sequence == e.Value.(SequenceValue).Sequence {
-								sequence.Count += e.Value.(SequenceValue).Count
-							}
+		} else {
 
-							bestSequenceList.Remove(e)
-						}
+			//last candle should be only used for pre-counts
+			for _, candle := range candles[:len(candles)-1] {
+				tracker.Add(candle)
+			}
 
-						bestSequenceList.PushFront(sequence)
-					}
+			tracker.Confirm(candles[len(candles)-1])
 
-				} else {
+			//remove element
+			candles = candles[:len(candles)-1]
 
-					bestPeriod = 0
-					up = 0
-					down = 0
-				}
+			//set new timestamp
+			if len(candles) > 0 {
 
+				newEndTimestamp = candles[len(candles)-1].OpenTime
 			}
-
-			rsiP.AddPoint(candle.ClosePrice)
 		}
 
 		if prevCandle.OpenTime == 0 {
 			LastRSI = rsiP
 		}
 
-		maxValue := commonBestSequenceList.Back()
-
-		for e := bestSequenceList.Front(); e != nil; e = e.Next() {
-
-			if maxValue != nil {
-
-				if maxValue.Value.(SequenceValue).Sequence == e.Value.(SequenceValue).Sequence {
-
-					val := maxValue.Value.(SequenceValue)
-					val.Count += e.Value.(SequenceValue).Count
-
-					log.Println("Added Old Data: ", interval, e.Value, maxValue, val)
-					commonBestSequenceList.Remove(maxValue)
-					commonBestSequenceList.PushBack(val)
-
-				} else if maxValue.Value.(SequenceValue).Sequence < e.Value.(SequenceValue).Sequence {
-
-					log.Println("Added Old Data Without Merge: ", interval, e.Value, maxValue)
-					commonBestSequenceList.PushBack(e.Value)
-
-				}
-
-			} else {
-
-				commonBestSequenceList.PushBack(e.Value)
-			}
-
-			maxValue = commonBestSequenceList.Back()
-		}
+		MergeSequences(commonBestSequenceList, tracker.List())
 
 		if len(candles) == 0 || candles[0].PrevCloseCandleTimestamp == 0 || done {
 			break
@@ -298,65 +201,21 @@ func GetSequncesWithUpdate(symbol string, interval candlescommon.Interval, times
 
 		prevCandle = candles[0]
 
+		nextCandles = append(append([]candlescommon.KLine(nil), batch...), nextCandles...)
+
+		if delay := tracker.maxDelay(); len(nextCandles) > delay {
+			nextCandles = nextCandles[:delay]
+		}
+
 		log.Println(symbol, interval, prevCandle.OpenTime, lastKlineTimestamp, timestamp)
 	}
 
 	if newEndTimestamp > lastKlineTimestamp {
 
 		//Merge Sequences
-		maxValue := commonBestSequenceList.Back()
-
-		for e := lastSavedSequences.Front(); e != nil; e = e.Next() {
-
-			if maxValue != nil {
-
-				if maxValue.Value.(SequenceValue).Sequence == e.Value.(SequenceValue).Sequence {
-
-					val := maxValue.Value.(SequenceValue)
-					val.Count += e.Value.(SequenceValue).Count
-
-					log.Println("Merge Old Data: ", interval, e.Value, maxValue, val)
-
-					commonBestSequenceList.Remove(maxValue)
-					commonBestSequenceList.PushBack(val)
-
-				} else if maxValue.Value.(SequenceValue).Sequence < e.Value.(SequenceValue).Sequence {
-
-					log.Println("Global Merge: ", interval, e.Value, maxValue)
-
-					commonBestSequenceList.PushBack(e.Value)
-
-				}
-
-			} else {
-
-				commonBestSequenceList.PushBack(e.Value)
-			}
-
-			maxValue = commonBestSequenceList.Back()
-		}
-
-		var sequences = make([]SequenceValue, 0)
-
-		for e := commonBestSequenceList.Front(); e != nil; e = e.Next() {
-			sequences = append(sequences, e.Value.(SequenceValue))
-		}
-
-		js, err := json.Marshal(sequences)
-
-		if err != nil {
-
-			return nil, 0, nil, err
-		}
-
-		lastRSIJSON, err := json.Marshal(LastRSI)
-
-		if err != nil {
-
-			return nil, 0, nil, err
-		}
+		MergeSequences(commonBestSequenceList, lastSavedSequences)
 
-		_, err = database.DatabaseManager.Exec(`INSERT INTO public."tran_bestPeriodsList"(symbol, "interval", "list","lastUpdate","lastRSI") VALUES ($1, $2, $3,$4,$5);`, symbol, fmt.Sprintf("%d%s", interval.Duration, interval.Letter), js, newEndTimestamp, lastRSIJSON)
+		err := SaveSequences(symbol, fmt.Sprintf("%d%s", interval.Duration, interval.Letter), centralRSI, options, commonBestSequenceList, newEndTimestamp, LastRSI)
 
 		if err != nil {
 
@@ -0,0 +1,12 @@
+package manager
+
+import (
+	"github.com/NERON/tran/candlescommon"
+	"github.com/NERON/tran/indicators"
+)
+
+// GetRSIWarmUpKLines returns candles before timestamp that are enough for RSI of all periods to converge,
+// all endpoints use it so the same candle gets the same best period everywhere
+func GetRSIWarmUpKLines(symbol string, interval candlescommon.Interval, timestamp uint64, rsip *indicators.RSIMultiplePeriods) ([]candlescommon.KLine, error) {
+	return GetLastKLinesFromTimestamp(symbol, interval, timestamp, rsip.WarmUpPoints(indicators.DefaultWarmUpTolerance))
+}
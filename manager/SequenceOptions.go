@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"github.com/NERON/tran/indicators"
+	"sort"
+	"strings"
+)
+
+// DefaultHighCentralRSI is central RSI of high sequences when request doesn't set it
+const DefaultHighCentralRSI = 85
+
+var errHighPivots = errors.New("highs are found only by the default rule")
+var errNoPatterns = errors.New("pivot patterns are not recognized")
+
+// SequenceOptions select pivots that are counted in sequences, zero options count lows of the default rule
+type SequenceOptions struct {
+	//highs are counted on resistance zones of overbought central RSI
+	High bool
+
+	//low detector like fractal:2:2, zigzag:5 or zigzagatr:14:3, empty is the default rule
+	Pivots string
+
+	//pivot is counted only if one of patterns ends on it or on the next candle, nil counts all pivots
+	Patterns          []string
+	PatternConfidence float64
+}
+
+// Key is saved with snapshots, zero options have empty key like snapshots saved before options
+func (options SequenceOptions) Key() string {
+
+	parts := make([]string, 0)
+
+	if options.High {
+		parts = append(parts, "high")
+	}
+
+	if options.Pivots != "" {
+		parts = append(parts, "pivots="+options.Pivots)
+	}
+
+	if options.Patterns != nil {
+
+		names := append([]string(nil), options.Patterns...)
+		sort.Strings(names)
+
+		parts = append(parts, fmt.Sprintf("patterns=%s:%g", strings.Join(names, ","), options.PatternConfidence))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// DefaultCentralRSI is central RSI of side of options
+func (options SequenceOptions) DefaultCentralRSI() uint {
+
+	if options.High {
+		return DefaultHighCentralRSI
+	}
+
+	return DefaultCentralRSI
+}
+
+// Validate checks that tracker can be created with options
+func (options SequenceOptions) Validate() error {
+
+	if options.Patterns != nil && len(options.Patterns) == 0 {
+		return errNoPatterns
+	}
+
+	//detector is created without RSI
+	_, err := options.NewTracker(nil, 0)
+
+	return err
+}
+
+// NewTracker creates tracker of pivots selected by options
+func (options SequenceOptions) NewTracker(rsip *indicators.RSIMultiplePeriods, centralRSI float64) (*SequenceTracker, error) {
+
+	var tracker *SequenceTracker
+
+	if options.High {
+
+		if options.Pivots != "" {
+			return nil, errHighPivots
+		}
+
+		tracker = NewHighSequenceTracker(rsip, centralRSI)
+
+	} else {
+
+		var err error
+
+		tracker, err = NewSequenceTracker(rsip, options.Pivots, centralRSI)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tracker.Patterns = options.Patterns
+	tracker.PatternConfidence = options.PatternConfidence
+
+	return tracker, nil
+}
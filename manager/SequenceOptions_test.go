@@ -0,0 +1,23 @@
+package manager
+
+import (
+	"testing"
+)
+
+func TestSequenceOptionsHighPivots(t *testing.T) {
+
+	for _, pivots := range []string{"fractal:2:2", "zigzag:3", "zigzagatr:14:3"} {
+
+		if err := (SequenceOptions{High: true, Pivots: pivots}).Validate(); err != errHighPivots {
+			t.Errorf("highs of %s returned %v", pivots, err)
+		}
+
+		if err := (SequenceOptions{Pivots: pivots}).Validate(); err != nil {
+			t.Errorf("lows of %s returned %v", pivots, err)
+		}
+	}
+
+	if err := (SequenceOptions{High: true}).Validate(); err != nil {
+		t.Errorf("highs of the default rule returned %v", err)
+	}
+}
@@ -0,0 +1,301 @@
+package manager
+
+import (
+	"container/list"
+	"database/sql"
+	"encoding/json"
+	"github.com/NERON/tran/database"
+	"github.com/NERON/tran/indicators"
+	"github.com/lib/pq"
+	"log"
+)
+
+const sequenceEntryColumns = `e.sequence, e."lowCentralPrice", e."centralPrice", e.fictive, e."timestamp", e.central, e.lower, e.up, e.down, e.count, e."insufficientHistory", e."ladderPosition"`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSequenceValue(row rowScanner) (SequenceValue, error) {
+
+	var sequence SequenceValue
+
+	err := row.Scan(&sequence.Sequence, &sequence.LowCentralPrice, &sequence.CentralPrice, &sequence.Fictive, &sequence.Timestamp, &sequence.Central, &sequence.Lower, &sequence.Up, &sequence.Down, &sequence.Count, &sequence.InsufficientHistory, &sequence.LadderPosition)
+
+	return sequence, err
+}
+
+// GetPeriodsFromDatabase returns the latest snapshot of options saved before timestamp: sequences, open time of the last counted candle and RSI after it
+func GetPeriodsFromDatabase(symbol string, interval string, centralRSI uint, options SequenceOptions, timestamp int64) (*list.List, uint64, *indicators.RSIMultiplePeriods, error) {
+
+	var snapshotID int64
+	var lastUpdate uint64
+
+	RSI := &indicators.RSIMultiplePeriods{}
+
+	err := database.DatabaseManager.QueryRow(`SELECT id, "lastUpdate", smoothing, source, "pointsCount", "lastValue", gains, losses FROM public.tran_sequence_snapshots WHERE symbol=$1 AND "interval"=$2 AND "centralRSI"=$3 AND options=$4 AND "lastUpdate" < $5 ORDER BY "lastUpdate" DESC LIMIT 1;`, symbol, interval, centralRSI, options.Key(), timestamp).Scan(&snapshotID, &lastUpdate, &RSI.Smoothing, &RSI.Source, &RSI.PointsCount, &RSI.LastValue, pq.Array(&RSI.Gains), pq.Array(&RSI.Losses))
+
+	if err != nil && err != sql.ErrNoRows {
+		return nil, 0, nil, err
+	}
+
+	if err == sql.ErrNoRows {
+		return list.New(), 0, nil, nil
+	}
+
+	sequenceList, err := getSnapshotSequences(snapshotID)
+
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	rows, err := database.DatabaseManager.Query(`SELECT period, "avgGain", "avgLoss" FROM public.tran_sequence_rsi WHERE "snapshotId"=$1 ORDER BY period;`, snapshotID)
+
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+
+		var period uint
+		var avgGain, avgLoss float64
+
+		err = rows.Scan(&period, &avgGain, &avgLoss)
+
+		if err != nil {
+			return nil, 0, nil, err
+		}
+
+		RSI.Periods = append(RSI.Periods, period)
+		RSI.AvgGains = append(RSI.AvgGains, avgGain)
+		RSI.AvgLosses = append(RSI.AvgLosses, avgLoss)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, nil, err
+	}
+
+	return sequenceList, lastUpdate, RSI, nil
+}
+
+func getSnapshotSequences(snapshotID int64) (*list.List, error) {
+
+	rows, err := database.DatabaseManager.Query(`SELECT `+sequenceEntryColumns+` FROM public.tran_sequence_entries e WHERE e."snapshotId"=$1 ORDER BY e."position";`, snapshotID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sequenceList := list.New()
+
+	for rows.Next() {
+
+		sequence, err := scanSequenceValue(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sequenceList.PushBack(sequence)
+	}
+
+	return sequenceList, rows.Err()
+}
+
+// SaveSequences saves snapshot of sequences of options counted up to candle lastUpdate and RSI after this candle,
+// snapshot that is already saved isn't changed
+func SaveSequences(symbol string, interval string, centralRSI uint, options SequenceOptions, sequenceList *list.List, lastUpdate uint64, rsip *indicators.RSIMultiplePeriods) error {
+
+	var sequences = make([]SequenceValue, 0)
+
+	for e := sequenceList.Front(); e != nil; e = e.Next() {
+		sequences = append(sequences, e.Value.(SequenceValue))
+	}
+
+	return saveSnapshot(symbol, interval, centralRSI, options, sequences, lastUpdate, rsip)
+}
+
+func saveSnapshot(symbol string, interval string, centralRSI uint, options SequenceOptions, sequences []SequenceValue, lastUpdate uint64, rsip *indicators.RSIMultiplePeriods) error {
+
+	tx, err := database.DatabaseManager.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	//rollback does nothing after commit
+	defer tx.Rollback()
+
+	err = insertSnapshot(tx, symbol, interval, centralRSI, options, sequences, lastUpdate, rsip)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertSnapshot(tx *sql.Tx, symbol string, interval string, centralRSI uint, options SequenceOptions, sequences []SequenceValue, lastUpdate uint64, rsip *indicators.RSIMultiplePeriods) error {
+
+	var snapshotID int64
+
+	err := tx.QueryRow(`INSERT INTO public.tran_sequence_snapshots(symbol, "interval", "centralRSI", options, "lastUpdate", smoothing, source, "pointsCount", "lastValue", gains, losses) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) ON CONFLICT DO NOTHING RETURNING id;`, symbol, interval, centralRSI, options.Key(), lastUpdate, rsip.Smoothing, rsip.Source, rsip.PointsCount, rsip.LastValue, pq.Array(rsip.Gains), pq.Array(rsip.Losses)).Scan(&snapshotID)
+
+	//snapshot for this candle is already saved
+	if err == sql.ErrNoRows {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	entryStmt, err := tx.Prepare(`INSERT INTO public.tran_sequence_entries("snapshotId", "position", sequence, "lowCentralPrice", "centralPrice", fictive, "timestamp", central, lower, up, down, count, "insufficientHistory", "ladderPosition") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14);`)
+
+	if err != nil {
+		return err
+	}
+
+	defer entryStmt.Close()
+
+	for position, sequence := range sequences {
+
+		_, err = entryStmt.Exec(snapshotID, position, sequence.Sequence, sequence.LowCentralPrice, sequence.CentralPrice, sequence.Fictive, sequence.Timestamp, sequence.Central, sequence.Lower, sequence.Up, sequence.Down, sequence.Count, sequence.InsufficientHistory, sequence.LadderPosition)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	rsiStmt, err := tx.Prepare(`INSERT INTO public.tran_sequence_rsi("snapshotId", period, "avgGain", "avgLoss") VALUES ($1, $2, $3, $4);`)
+
+	if err != nil {
+		return err
+	}
+
+	defer rsiStmt.Close()
+
+	for idx, period := range rsip.Periods {
+
+		_, err = rsiStmt.Exec(snapshotID, period, rsip.AvgGains[idx], rsip.AvgLosses[idx])
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LastSequenceAppearance returns the latest sequence of period with count more than minCount among saved snapshots of options
+func LastSequenceAppearance(symbol string, interval string, centralRSI uint, options SequenceOptions, period int, minCount uint) (SequenceValue, bool, error) {
+
+	row := database.DatabaseManager.QueryRow(`SELECT `+sequenceEntryColumns+` FROM public.tran_sequence_entries e JOIN public.tran_sequence_snapshots s ON s.id = e."snapshotId" WHERE s.symbol=$1 AND s."interval"=$2 AND s."centralRSI"=$3 AND s.options=$4 AND e.sequence=$5 AND e.count>$6 ORDER BY e."timestamp" DESC LIMIT 1;`, symbol, interval, centralRSI, options.Key(), period, minCount)
+
+	sequence, err := scanSequenceValue(row)
+
+	if err == sql.ErrNoRows {
+		return SequenceValue{}, false, nil
+	}
+
+	if err != nil {
+		return SequenceValue{}, false, err
+	}
+
+	return sequence, true, nil
+}
+
+// MigrateSequenceSnapshots moves snapshots saved as JSON in tran_bestPeriodsList to snapshot tables.
+// Legacy row is deleted in the same transaction as its copy is saved, so snapshots removed by retention later
+// aren't copied again. Rows that can't be parsed are left in tran_bestPeriodsList
+func MigrateSequenceSnapshots() (int, error) {
+
+	//rows copied before they were deleted on migration
+	_, err := database.DatabaseManager.Exec(`DELETE FROM public."tran_bestPeriodsList" b WHERE EXISTS (SELECT 1 FROM public.tran_sequence_snapshots s WHERE s.symbol=b.symbol AND s."interval"=b."interval" AND s."centralRSI"=b."centralRSI" AND s.options='' AND s."lastUpdate"=b."lastUpdate");`)
+
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := database.DatabaseManager.Query(`SELECT symbol, "interval", "centralRSI", list, "lastUpdate", "lastRSI" FROM public."tran_bestPeriodsList" ORDER BY "lastUpdate";`)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	migrated := 0
+
+	for rows.Next() {
+
+		var symbol, interval, listJSon, RSIJSon string
+		var centralRSI uint
+		var lastUpdate uint64
+
+		err = rows.Scan(&symbol, &interval, &centralRSI, &listJSon, &lastUpdate, &RSIJSon)
+
+		if err != nil {
+			return migrated, err
+		}
+
+		var sequences []SequenceValue
+
+		err = json.Unmarshal([]byte(listJSon), &sequences)
+
+		if err != nil {
+			log.Println("Wrong saved sequences: ", symbol, interval, lastUpdate, err.Error())
+			continue
+		}
+
+		var RSI indicators.RSIMultiplePeriods
+
+		err = RSI.Unmarshal([]byte(RSIJSon))
+
+		if err != nil {
+			log.Println("Wrong saved RSI: ", symbol, interval, lastUpdate, err.Error())
+			continue
+		}
+
+		err = moveLegacySnapshot(symbol, interval, centralRSI, sequences, lastUpdate, &RSI)
+
+		if err != nil {
+			return migrated, err
+		}
+
+		migrated++
+	}
+
+	return migrated, rows.Err()
+}
+
+// moveLegacySnapshot saves legacy snapshot as lows of the default rule and deletes it from tran_bestPeriodsList
+func moveLegacySnapshot(symbol string, interval string, centralRSI uint, sequences []SequenceValue, lastUpdate uint64, rsip *indicators.RSIMultiplePeriods) error {
+
+	tx, err := database.DatabaseManager.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	//rollback does nothing after commit
+	defer tx.Rollback()
+
+	err = insertSnapshot(tx, symbol, interval, centralRSI, SequenceOptions{}, sequences, lastUpdate, rsip)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM public."tran_bestPeriodsList" WHERE symbol=$1 AND "interval"=$2 AND "centralRSI"=$3 AND "lastUpdate"=$4;`, symbol, interval, centralRSI, lastUpdate)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
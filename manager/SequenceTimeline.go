@@ -0,0 +1,211 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/NERON/tran/candlescommon"
+	"github.com/NERON/tran/indicators"
+	"sort"
+	"time"
+)
+
+// MaxTimelineCandles is the most candles timeline counts, including candles between snapshot and range
+const MaxTimelineCandles = 50000
+
+var errTimelineTooLong = fmt.Errorf("timeline should count at most %d candles, narrow range with from and to", MaxTimelineCandles)
+
+const (
+	SequenceEventPush  = "push"
+	SequenceEventPop   = "pop"
+	SequenceEventMerge = "merge"
+)
+
+// SequenceEvent is change of the stack caused by Candle that confirmed low.
+// Pop removes shorter sequence, merge removes sequence of the same period and adds its count to the pushed one
+type SequenceEvent struct {
+	Type     string
+	Candle   candlescommon.KLine
+	Sequence SequenceValue
+}
+
+// SequenceTimeline is stack before the first candle of range and its changes in order
+type SequenceTimeline struct {
+	Initial []SequenceValue
+	Events  []SequenceEvent
+}
+
+func sequenceEvents(push SequencePush, candle candlescommon.KLine) []SequenceEvent {
+
+	events := make([]SequenceEvent, 0, len(push.Removed)+1)
+
+	for _, removed := range push.Removed {
+
+		eventType := SequenceEventPop
+
+		if removed.Sequence == push.Sequence.Sequence {
+			eventType = SequenceEventMerge
+		}
+
+		events = append(events, SequenceEvent{Type: eventType, Candle: candle, Sequence: removed})
+	}
+
+	return append(events, SequenceEvent{Type: SequenceEventPush, Candle: candle, Sequence: push.Sequence})
+}
+
+// GetSequenceTimeline returns how stack of options changed on closed candles from timestamp from to timestamp to,
+// range starts from the candle that contains from. Counting starts from the nearest saved snapshot before range,
+// without snapshot the stack starts empty on the first candle of range
+func GetSequenceTimeline(symbol string, interval candlescommon.Interval, centralRSI uint, options SequenceOptions, from uint64, to uint64) (*SequenceTimeline, error) {
+
+	now := uint64(time.Now().UnixMilli())
+
+	from = alignOpenTime(interval, from)
+
+	if duration := interval.Milliseconds(); duration > 0 && minTimestamp(to, now) > from && (minTimestamp(to, now)-from)/duration > MaxTimelineCandles {
+		return nil, errTimelineTooLong
+	}
+
+	sequences, lastUpdate, rsip, err := GetPeriodsFromDatabase(symbol, fmt.Sprintf("%d%s", interval.Duration, interval.Letter), centralRSI, options, int64(from))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tracker *SequenceTracker
+
+	//candles are loaded after start, it's close time of the candle before range, so grouped candles are whole
+	start := uint64(0)
+
+	if from > 0 {
+		start = from - 1
+	}
+
+	if rsip == nil {
+
+		rsip = indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod)
+
+		candlesOld, err := GetRSIWarmUpKLines(symbol, interval, from, rsip)
+
+		if err != nil {
+			return nil, err
+		}
+
+		tracker, err = options.NewTracker(rsip, float64(centralRSI))
+
+		if err != nil {
+			return nil, err
+		}
+
+		tracker.Prime(candlesOld)
+
+	} else {
+
+		tracker, err = options.NewTracker(rsip, float64(centralRSI))
+
+		if err != nil {
+			return nil, err
+		}
+
+		tracker.Continue(sequences, lastUpdate)
+
+		//detector gets the last counted candles for lows right after them
+		counted, err := GetLastKLinesFromTimestamp(symbol, interval, lastUpdate+1, tracker.warmUpCandles())
+
+		if err != nil {
+			return nil, err
+		}
+
+		tracker.PrimeDetector(counted)
+
+		start = interval.ExpectedCloseTime(lastUpdate)
+	}
+
+	timeline := &SequenceTimeline{Events: make([]SequenceEvent, 0)}
+
+	candlesCount := 0
+
+	for {
+
+		candles, err := GetKLinesInRange(symbol, interval, start, to, 1000)
+
+		if err != nil {
+			return nil, err
+		}
+
+		//only closed candles of range are counted
+		idx := sort.Search(len(candles), func(i int) bool {
+			return candles[i].OpenTime > to || candles[i].CloseTime >= now
+		})
+
+		candles = candles[:idx]
+
+		if len(candles) == 0 {
+			break
+		}
+
+		candlesCount += len(candles)
+
+		if candlesCount > MaxTimelineCandles {
+			return nil, errTimelineTooLong
+		}
+
+		for _, candle := range candles {
+
+			if candle.OpenTime >= from && timeline.Initial == nil {
+				timeline.Initial = tracker.Sequences()
+			}
+
+			for _, push := range tracker.Add(candle) {
+
+				if candle.OpenTime >= from {
+					timeline.Events = append(timeline.Events, sequenceEvents(push, candle)...)
+				}
+			}
+		}
+
+		start = candles[len(candles)-1].CloseTime
+	}
+
+	if timeline.Initial == nil {
+		timeline.Initial = tracker.Sequences()
+	}
+
+	return timeline, nil
+}
+
+// alignOpenTime returns open time of candle of interval that contains timestamp
+func alignOpenTime(interval candlescommon.Interval, timestamp uint64) uint64 {
+
+	switch interval.Letter {
+
+	case "M":
+		t := time.Unix(int64(timestamp/1000), 0).UTC()
+		return uint64(time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).Unix()) * 1000
+
+	case "w":
+		//weeks start from monday, first monday after epoch is 4 days later
+		offset := uint64(4 * 24 * 60 * 60 * 1000)
+
+		if timestamp < offset {
+			return 0
+		}
+
+		return offset + (timestamp-offset)/interval.Milliseconds()*interval.Milliseconds()
+	}
+
+	duration := interval.Milliseconds()
+
+	if duration == 0 {
+		return timestamp
+	}
+
+	return timestamp / duration * duration
+}
+
+func minTimestamp(a uint64, b uint64) uint64 {
+
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,622 @@
+package manager
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"github.com/NERON/tran/candlescommon"
+	"github.com/NERON/tran/candlescommon/patterns"
+	"github.com/NERON/tran/indicators"
+	"sort"
+)
+
+// patternCandles is the most candles of pattern, the longest patterns are stars
+const patternCandles = 3
+
+// DefaultMaxLowDelay is how many candles back tracker keeps RSI of pending lows for detectors that confirm lows without limit
+const DefaultMaxLowDelay = 100
+
+var errNotCandleDetector = errors.New("sequences can be tracked only with candle low detector")
+
+// SequencePush is sequence pushed to the stack and sequences it removed from the stack
+type SequencePush struct {
+	Sequence SequenceValue
+	Removed  []SequenceValue
+}
+
+type trackerPoint struct {
+	Index  int
+	Candle candlescommon.KLine
+	RSI    *indicators.RSIMultiplePeriods
+}
+
+// SequenceTracker finds best RSI period on every low and keeps stack of sequences from the shortest period to the longest.
+// Sequence removes all sequences with the same or shorter period, count of the same period is added to it
+type SequenceTracker struct {
+	RSI        *indicators.RSIMultiplePeriods
+	Detector   indicators.PivotLowDetector
+	Pivots     string
+	CentralRSI float64
+
+	//highs are searched with overbought central RSI
+	High bool
+
+	//timestamp of the last pushed pivot, pivot confirmed again isn't pushed twice
+	LastPivot uint64
+
+	//pivots are pushed only if pattern with PatternConfidence ends on pivot candle or on the next candle
+	Patterns          []string
+	PatternConfidence float64
+
+	index     int
+	sequences *list.List
+
+	//RSI before candles that detector can still confirm as lows, lows are confirmed after RSI got next candles.
+	//RSI of dropped points is reused by the next points
+	history []trackerPoint
+	spare   []*indicators.RSIMultiplePeriods
+
+	//the last candles for patterns and indexes of pivots that wait for the next candle to check patterns
+	candles []candlescommon.KLine
+	waiting []int
+}
+
+type sequenceTrackerState struct {
+	Sequences  []SequenceValue
+	RSI        *indicators.RSIMultiplePeriods
+	Detector   json.RawMessage `json:",omitempty"`
+	Pivots     string
+	CentralRSI float64
+	High       bool
+	LastPivot  uint64
+	Index      int
+	History    []trackerPoint
+
+	Patterns          []string              `json:",omitempty"`
+	PatternConfidence float64               `json:",omitempty"`
+	Candles           []candlescommon.KLine `json:",omitempty"`
+	Waiting           []int                 `json:",omitempty"`
+}
+
+// NewSequenceTracker creates tracker of lows found by pivots detector, empty pivots is the default rule
+func NewSequenceTracker(rsip *indicators.RSIMultiplePeriods, pivots string, centralRSI float64) (*SequenceTracker, error) {
+
+	lowReverse, err := indicators.NewLowDetectorFromString(pivots)
+
+	if err != nil {
+		return nil, err
+	}
+
+	detector, ok := lowReverse.(indicators.PivotLowDetector)
+
+	if !ok {
+		return nil, errNotCandleDetector
+	}
+
+	return &SequenceTracker{RSI: rsip, Detector: detector, Pivots: pivots, CentralRSI: centralRSI, sequences: list.New()}, nil
+}
+
+// NewHighSequenceTracker creates tracker of highs found by the default rule of highs
+func NewHighSequenceTracker(rsip *indicators.RSIMultiplePeriods, centralRSI float64) *SequenceTracker {
+	return &SequenceTracker{RSI: rsip, Detector: indicators.NewDefaultHighDetector(), CentralRSI: centralRSI, High: true, sequences: list.New()}
+}
+
+// Continue starts tracker from saved sequences, candles up to lastUpdate were already counted
+func (t *SequenceTracker) Continue(sequences *list.List, lastUpdate uint64) {
+
+	t.sequences = list.New()
+
+	for e := sequences.Front(); e != nil; e = e.Next() {
+		t.sequences.PushBack(e.Value)
+	}
+
+	t.LastPivot = lastUpdate
+}
+
+// Prime passes candles before tracked candles to RSI and detector, lows among them aren't counted.
+// Detector is primed like PrimeLowDetector, so the first tracked candle has the same lows as in GenerateMapLows
+func (t *SequenceTracker) Prime(candles []candlescommon.KLine) {
+
+	for _, candle := range candles {
+		t.RSI.AddCandle(candle)
+	}
+
+	if t.Detector != nil {
+		indicators.PrimeLowDetector(t.Detector, candles)
+	}
+
+	for _, candle := range candles {
+		t.addPatternCandle(candle)
+	}
+
+	t.index += len(candles)
+	t.history = t.history[:0]
+}
+
+// Resume primes tracker with candles that were counted up to lastPivot. The last of them can have lows
+// that only the next candles confirm, so they are counted again and lows that were already pushed are skipped
+func (t *SequenceTracker) Resume(candles []candlescommon.KLine, lastPivot uint64) {
+
+	recounted := t.maxDelay()
+
+	if recounted > len(candles) {
+		recounted = len(candles)
+	}
+
+	t.Prime(candles[:len(candles)-recounted])
+	t.LastPivot = lastPivot
+
+	for _, candle := range candles[len(candles)-recounted:] {
+		t.Add(candle)
+	}
+}
+
+// PrimeDetector passes candles only to detector, it's used when RSI already contains them
+// and tracker continues counted candles
+func (t *SequenceTracker) PrimeDetector(candles []candlescommon.KLine) {
+
+	for _, candle := range candles {
+
+		if t.Detector != nil {
+			t.Detector.AddCandle(candle)
+		}
+
+		t.addPatternCandle(candle)
+		t.index++
+	}
+
+	t.history = t.history[:0]
+	t.waiting = t.waiting[:0]
+}
+
+// Add passes candle to detector, pushes lows it confirmed and adds candle to RSI
+func (t *SequenceTracker) Add(candle candlescommon.KLine) []SequencePush {
+
+	pushes := t.confirm(candle, true)
+
+	t.keepPending(candle)
+
+	t.RSI.AddCandle(candle)
+	t.index++
+
+	return pushes
+}
+
+// Confirm passes candles that only confirm previous lows, candles themselves aren't counted.
+// They are the last candles of tracker, no candles should be added after them
+func (t *SequenceTracker) Confirm(candles ...candlescommon.KLine) []SequencePush {
+
+	pushes := make([]SequencePush, 0)
+
+	//RSI isn't kept for not counted candles, so their lows aren't found in history
+	for _, candle := range candles {
+
+		pushes = append(pushes, t.confirm(candle, false)...)
+		t.index++
+	}
+
+	return pushes
+}
+
+func (t *SequenceTracker) maxDelay() int {
+
+	if t.Detector == nil {
+		return 0
+	}
+
+	delay := t.Detector.ConfirmationDelay()
+
+	if delay < 0 {
+		return DefaultMaxLowDelay
+	}
+
+	return delay
+}
+
+// warmUpCandles is how many last counted candles detector needs to continue them
+func (t *SequenceTracker) warmUpCandles() int {
+
+	if t.Detector == nil {
+		return 0
+	}
+
+	warmUp := t.Detector.WarmUpCandles()
+
+	if warmUp < 0 {
+		return DefaultMaxLowDelay
+	}
+
+	return warmUp
+}
+
+// keepPending keeps RSI only before lows detector reports as pending and before pivots that wait for patterns
+func (t *SequenceTracker) keepPending(candle candlescommon.KLine) {
+
+	delay := t.maxDelay()
+
+	if delay == 0 {
+		return
+	}
+
+	pending := t.Detector.PendingLows()
+	kept := t.history[:0]
+
+	for _, point := range t.history {
+
+		if t.index-point.Index < delay && t.isPending(point.Index, pending) {
+			kept = append(kept, point)
+		} else {
+			t.spare = append(t.spare, point.RSI)
+		}
+	}
+
+	t.history = kept
+
+	if !t.isPending(t.index, pending) {
+		return
+	}
+
+	var rsip *indicators.RSIMultiplePeriods
+
+	if len(t.spare) > 0 {
+
+		rsip = t.spare[len(t.spare)-1]
+		t.spare = t.spare[:len(t.spare)-1]
+		rsip.CopyFrom(t.RSI)
+
+	} else {
+		rsip = t.RSI.Clone().(*indicators.RSIMultiplePeriods)
+	}
+
+	t.history = append(t.history, trackerPoint{Index: t.index, Candle: candle, RSI: rsip})
+}
+
+func (t *SequenceTracker) isPending(index int, pending []int) bool {
+
+	for _, offset := range pending {
+
+		if t.index-offset == index {
+			return true
+		}
+	}
+
+	for _, waiting := range t.waiting {
+
+		if waiting == index {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *SequenceTracker) confirm(candle candlescommon.KLine, counted bool) []SequencePush {
+
+	pushes := make([]SequencePush, 0)
+
+	if t.Detector == nil {
+		return pushes
+	}
+
+	t.Detector.AddCandle(candle)
+	t.addPatternCandle(candle)
+
+	offsets := append([]int(nil), t.Detector.ConfirmedLows()...)
+
+	//pivots that waited for this candle are checked again
+	for _, index := range t.waiting {
+		offsets = append(offsets, t.index-index)
+	}
+
+	t.waiting = t.waiting[:0]
+
+	//older lows are pushed first
+	sort.Sort(sort.Reverse(sort.IntSlice(offsets)))
+
+	for idx, offset := range offsets {
+
+		if idx > 0 && offsets[idx-1] == offset {
+			continue
+		}
+
+		lowCandle, rsip, ok := candle, t.RSI, counted
+
+		if offset > 0 {
+			lowCandle, rsip, ok = t.point(t.index - offset)
+		}
+
+		if !ok || lowCandle.OpenTime <= t.LastPivot {
+			continue
+		}
+
+		if t.Patterns != nil && !t.patternConfirmed(offset) {
+
+			//pattern can still end on the next candle
+			if offset == 0 {
+				t.waiting = append(t.waiting, t.index)
+			}
+
+			continue
+		}
+
+		sequence, _, accepted := t.evaluate(rsip, lowCandle)
+
+		if accepted {
+			pushes = append(pushes, t.Push(sequence))
+		}
+	}
+
+	return pushes
+}
+
+// addPatternCandle keeps candles of patterns that end on pivots which can still be confirmed and on the candle after them
+func (t *SequenceTracker) addPatternCandle(candle candlescommon.KLine) {
+
+	if t.Patterns == nil {
+		return
+	}
+
+	t.candles = append(t.candles, candle)
+
+	if keep := t.maxDelay() + patternCandles + 1; len(t.candles) > keep {
+		t.candles = append(t.candles[:0], t.candles[len(t.candles)-keep:]...)
+	}
+}
+
+// patternConfirmed checks patterns of pivot offset candles back, the last candle is the newest known candle,
+// so pivot of the last candle is checked only on itself
+func (t *SequenceTracker) patternConfirmed(offset int) bool {
+
+	idx := len(t.candles) - 1 - offset
+
+	if idx < 0 {
+		return false
+	}
+
+	return patterns.ConfirmsPivot(t.candles, idx, t.Patterns, t.PatternConfidence)
+}
+
+func (t *SequenceTracker) point(index int) (candlescommon.KLine, *indicators.RSIMultiplePeriods, bool) {
+
+	for _, point := range t.history {
+
+		if point.Index == index {
+			return point.Candle, point.RSI, true
+		}
+	}
+
+	return candlescommon.KLine{}, nil, false
+}
+
+// Evaluate returns sequence for pivot candle from RSI before the candle and position of pivot relative to the ladder,
+// false if pivot isn't inside zone of period longer than 2. Pivot outside of the ladder isn't counted,
+// it's reported by sequence with LadderPosition set
+func (t *SequenceTracker) Evaluate(candle candlescommon.KLine) (SequenceValue, indicators.LadderPosition, bool) {
+	return t.evaluate(t.RSI, candle)
+}
+
+func (t *SequenceTracker) evaluate(rsip *indicators.RSIMultiplePeriods, candle candlescommon.KLine) (SequenceValue, indicators.LadderPosition, bool) {
+
+	price := candle.LowPrice
+
+	var period int
+	var central float64
+	var position indicators.LadderPosition
+	var up, down float64
+
+	if t.High {
+
+		price = candle.HighPrice
+
+		period, central, position = rsip.FindPeriodHigh(price, t.CentralRSI)
+		up, down, _ = rsip.GetIntervalForPeriodHigh(period, t.CentralRSI)
+
+	} else {
+
+		period, central, position = rsip.FindPeriod(price, t.CentralRSI)
+		up, down, _ = rsip.GetIntervalForPeriod(period, t.CentralRSI)
+	}
+
+	if position != indicators.LadderInside {
+		return SequenceValue{Timestamp: candle.OpenTime, Lower: price, LadderPosition: position.String()}, position, false
+	}
+
+	if period < 2 || (period == 2 && ((!t.High && price > up) || (t.High && price < down))) {
+		return SequenceValue{}, position, false
+	}
+
+	sequence := SequenceValue{LowCentralPrice: true, Sequence: period, CentralPrice: central, Timestamp: candle.OpenTime, Central: central, Lower: price, Up: up, Down: down, Count: 1}
+	sequence.InsufficientHistory = !rsip.IsWarmedUp(indicators.DefaultWarmUpTolerance)
+
+	return sequence, position, true
+}
+
+// Push puts sequence on the stack, fictive sequence isn't counted itself
+func (t *SequenceTracker) Push(sequence SequenceValue) SequencePush {
+
+	if sequence.Fictive {
+		sequence.Count = 0
+	}
+
+	removed := make([]SequenceValue, 0)
+
+	for e := t.sequences.Front(); e != nil && e.Value.(SequenceValue).Sequence <= sequence.Sequence; e = t.sequences.Front() {
+
+		if sequence.Sequence == e.Value.(SequenceValue).Sequence {
+			sequence.Count += e.Value.(SequenceValue).Count
+		}
+
+		removed = append(removed, e.Value.(SequenceValue))
+		t.sequences.Remove(e)
+	}
+
+	t.sequences.PushFront(sequence)
+
+	if sequence.Timestamp > t.LastPivot {
+		t.LastPivot = sequence.Timestamp
+	}
+
+	return SequencePush{Sequence: sequence, Removed: removed}
+}
+
+// Sequences returns stack from the shortest period to the longest
+func (t *SequenceTracker) Sequences() []SequenceValue {
+
+	sequences := make([]SequenceValue, 0, t.sequences.Len())
+
+	for e := t.sequences.Front(); e != nil; e = e.Next() {
+		sequences = append(sequences, e.Value.(SequenceValue))
+	}
+
+	return sequences
+}
+
+// List returns copy of the stack
+func (t *SequenceTracker) List() *list.List {
+
+	sequences := list.New()
+
+	for e := t.sequences.Front(); e != nil; e = e.Next() {
+		sequences.PushBack(e.Value)
+	}
+
+	return sequences
+}
+
+// Clone returns tracker that continues independently
+func (t *SequenceTracker) Clone() *SequenceTracker {
+
+	clone := *t
+	clone.RSI = t.RSI.Clone().(*indicators.RSIMultiplePeriods)
+	clone.sequences = t.List()
+
+	//RSI of history is reused after point is dropped, so clone gets own copies
+	clone.history = make([]trackerPoint, 0, len(t.history))
+	clone.spare = nil
+
+	for _, point := range t.history {
+
+		point.RSI = point.RSI.Clone().(*indicators.RSIMultiplePeriods)
+		clone.history = append(clone.history, point)
+	}
+
+	clone.candles = append([]candlescommon.KLine(nil), t.candles...)
+	clone.waiting = append([]int(nil), t.waiting...)
+
+	if t.Detector != nil {
+		clone.Detector = t.Detector.Clone().(indicators.PivotLowDetector)
+	}
+
+	return &clone
+}
+
+// MergeOlder puts sequences found on candles before tracked candles under the stack
+func (t *SequenceTracker) MergeOlder(older *list.List) {
+	MergeSequences(t.sequences, older)
+}
+
+// MergeSequences appends older stack to newer, only periods longer than the longest newer period are left
+// and the same period adds count
+func MergeSequences(newer *list.List, older *list.List) {
+
+	maxValue := newer.Back()
+
+	for e := older.Front(); e != nil; e = e.Next() {
+
+		if maxValue == nil || maxValue.Value.(SequenceValue).Sequence < e.Value.(SequenceValue).Sequence {
+
+			newer.PushBack(e.Value)
+
+		} else if maxValue.Value.(SequenceValue).Sequence == e.Value.(SequenceValue).Sequence {
+
+			val := maxValue.Value.(SequenceValue)
+			val.Count += e.Value.(SequenceValue).Count
+
+			newer.Remove(maxValue)
+			newer.PushBack(val)
+		}
+
+		maxValue = newer.Back()
+	}
+}
+
+func (t *SequenceTracker) Marshal() ([]byte, error) {
+
+	state := sequenceTrackerState{
+		Sequences:  t.Sequences(),
+		RSI:        t.RSI,
+		Pivots:     t.Pivots,
+		CentralRSI: t.CentralRSI,
+		High:       t.High,
+		LastPivot:  t.LastPivot,
+		Index:      t.index,
+		History:    t.history,
+
+		Patterns:          t.Patterns,
+		PatternConfidence: t.PatternConfidence,
+		Candles:           t.candles,
+		Waiting:           t.waiting,
+	}
+
+	if t.Detector != nil {
+
+		detector, err := t.Detector.Marshal()
+
+		if err != nil {
+			return nil, err
+		}
+
+		state.Detector = detector
+	}
+
+	return json.Marshal(state)
+}
+
+func (t *SequenceTracker) Unmarshal(data []byte) error {
+
+	var state sequenceTrackerState
+
+	err := json.Unmarshal(data, &state)
+
+	if err != nil {
+		return err
+	}
+
+	restored := NewHighSequenceTracker(state.RSI, state.CentralRSI)
+
+	if !state.High {
+
+		restored, err = NewSequenceTracker(state.RSI, state.Pivots, state.CentralRSI)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	//trackers of highs were saved without detector
+	if len(state.Detector) > 0 {
+
+		err = restored.Detector.Unmarshal(state.Detector)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, sequence := range state.Sequences {
+		restored.sequences.PushBack(sequence)
+	}
+
+	restored.LastPivot = state.LastPivot
+	restored.index = state.Index
+	restored.history = state.History
+
+	restored.Patterns = state.Patterns
+	restored.PatternConfidence = state.PatternConfidence
+	restored.candles = state.Candles
+	restored.waiting = state.Waiting
+
+	*t = *restored
+
+	return nil
+}
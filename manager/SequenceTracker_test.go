@@ -0,0 +1,355 @@
+package manager
+
+import (
+	"container/list"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/NERON/tran/candlescommon"
+	"github.com/NERON/tran/candlescommon/patterns"
+	"github.com/NERON/tran/indicators"
+)
+
+func randomKLines(seed int64, count int) []candlescommon.KLine {
+
+	random := rand.New(rand.NewSource(seed))
+
+	klines := make([]candlescommon.KLine, 0, count)
+	price := 100.0
+
+	for i := 0; i < count; i++ {
+
+		open := price
+		price *= math.Exp(random.NormFloat64() * 0.01)
+
+		high := math.Max(open, price) * (1 + random.Float64()*0.005)
+		low := math.Min(open, price) * (1 - random.Float64()*0.005)
+
+		klines = append(klines, candlescommon.KLine{OpenTime: uint64(i) * 60000, CloseTime: uint64(i)*60000 + 59999, OpenPrice: open, ClosePrice: price, HighPrice: high, LowPrice: low, Closed: true})
+	}
+
+	return klines
+}
+
+// baselineSequences is stack of the handler before tracker: lows map of all candles, the last candle only confirms lows
+func baselineSequences(candlesOld []candlescommon.KLine, candles []candlescommon.KLine, pivots string, names []string, centralRSI float64) []SequenceValue {
+
+	rsiP := indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod)
+
+	for _, candleOld := range candlesOld {
+		rsiP.AddPoint(candleOld.ClosePrice)
+	}
+
+	detector, _ := indicators.NewLowDetectorFromString(pivots)
+
+	if pivots == "" {
+		detector = indicators.NewRSILowReverseIndicator()
+		detector.AddPoint(candlesOld[len(candlesOld)-1].LowPrice)
+	} else {
+		indicators.PrimeLowDetector(detector, candlesOld)
+	}
+
+	lowsMap := indicators.GenerateMapLows(detector, candles)
+	delete(lowsMap, len(candles)-1)
+
+	klines := append(append([]candlescommon.KLine(nil), candlesOld...), candles...)
+
+	bestSequenceList := list.New()
+
+	for idx, candle := range candles[:len(candles)-1] {
+
+		_, ok := lowsMap[idx]
+
+		if ok && names != nil {
+			ok = patterns.ConfirmsPivot(klines, len(candlesOld)+idx, names, 0)
+		}
+
+		if ok {
+
+			bestPeriod, _, centralPrice := rsiP.GetBestPeriod(candle.LowPrice, centralRSI)
+			up, down, _ := rsiP.GetIntervalForPeriod(bestPeriod, centralRSI)
+
+			if bestPeriod > 2 || (bestPeriod == 2 && candle.LowPrice <= up) {
+
+				sequence := SequenceValue{LowCentralPrice: true, Sequence: bestPeriod, CentralPrice: centralPrice, Timestamp: candle.OpenTime, Central: centralPrice, Lower: candle.LowPrice, Down: down, Count: 1}
+
+				for e := bestSequenceList.Front(); e != nil && e.Value.(SequenceValue).Sequence <= bestPeriod; e = bestSequenceList.Front() {
+
+					if sequence.Sequence == e.Value.(SequenceValue).Sequence {
+						sequence.Count += e.Value.(SequenceValue).Count
+					}
+
+					bestSequenceList.Remove(e)
+				}
+
+				bestSequenceList.PushFront(sequence)
+			}
+		}
+
+		rsiP.AddPoint(candle.ClosePrice)
+	}
+
+	sequences := make([]SequenceValue, 0, bestSequenceList.Len())
+
+	for e := bestSequenceList.Front(); e != nil; e = e.Next() {
+		sequences = append(sequences, e.Value.(SequenceValue))
+	}
+
+	return sequences
+}
+
+// withoutTrackerFields clears fields baseline didn't set
+func withoutTrackerFields(sequences []SequenceValue) []SequenceValue {
+
+	for i := range sequences {
+		sequences[i].Up = 0
+		sequences[i].InsufficientHistory = false
+		sequences[i].LadderPosition = ""
+	}
+
+	return sequences
+}
+
+func TestSequenceTrackerMatchesBaseline(t *testing.T) {
+
+	cases := []struct {
+		pivots     string
+		patterns   []string
+		centralRSI float64
+	}{
+		{pivots: "", centralRSI: DefaultCentralRSI},
+		{pivots: "", centralRSI: 20},
+		{pivots: "fractal:2:2", centralRSI: DefaultCentralRSI},
+		{pivots: "zigzag:3", centralRSI: DefaultCentralRSI},
+		{pivots: "", patterns: []string{patterns.BullishEngulfing, patterns.Hammer, patterns.BullishPinBar}, centralRSI: DefaultCentralRSI},
+	}
+
+	for _, c := range cases {
+
+		for seed := int64(1); seed <= 5; seed++ {
+
+			klines := randomKLines(seed, 1300)
+			candlesOld, candles := klines[:500], klines[500:]
+
+			expected := baselineSequences(candlesOld, candles, c.pivots, c.patterns, c.centralRSI)
+
+			tracker, err := SequenceOptions{Pivots: c.pivots, Patterns: c.patterns}.NewTracker(indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod), c.centralRSI)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tracker.Prime(candlesOld)
+
+			var clone *SequenceTracker
+
+			for i, candle := range candles[:len(candles)-1] {
+
+				if i == 200 {
+					clone = tracker.Clone()
+				}
+
+				//restored tracker continues the same stack
+				if i == 400 {
+
+					data, err := tracker.Marshal()
+
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					restored := &SequenceTracker{}
+
+					if err = restored.Unmarshal(data); err != nil {
+						t.Fatal(err)
+					}
+
+					tracker = restored
+				}
+
+				pushes := tracker.Add(candle)
+
+				if clone != nil {
+
+					if clonePushes := clone.Add(candle); !reflect.DeepEqual(pushes, clonePushes) {
+						t.Fatalf("%s seed %d: clone pushed %v on candle %d, tracker %v", c.pivots, seed, clonePushes, i, pushes)
+					}
+				}
+
+				//only RSI before pending lows is kept
+				if c.pivots == "" && c.patterns == nil && len(tracker.history) > 1 {
+					t.Fatalf("%s seed %d: history of %d points", c.pivots, seed, len(tracker.history))
+				}
+			}
+
+			tracker.Confirm(candles[len(candles)-1])
+
+			if found := withoutTrackerFields(tracker.Sequences()); !reflect.DeepEqual(found, expected) {
+				t.Fatalf("%s %v %g seed %d:\nsequences %+v\nbaseline  %+v", c.pivots, c.patterns, c.centralRSI, seed, found, expected)
+			}
+
+			if len(expected) == 0 {
+				t.Fatalf("%s seed %d: no sequences", c.pivots, seed)
+			}
+		}
+	}
+}
+
+// low below zones of all periods is reported, but stack isn't changed by it
+func TestSequenceTrackerReportsLowBelowLadder(t *testing.T) {
+
+	klines := randomKLines(1, 800)
+
+	tracker, err := SequenceOptions{}.NewTracker(indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod), DefaultCentralRSI)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tracker.Prime(klines[:500])
+
+	for _, candle := range klines[500:] {
+		tracker.Add(candle)
+	}
+
+	before := tracker.Sequences()
+
+	if len(before) == 0 {
+		t.Fatal("no sequences before low")
+	}
+
+	last := klines[len(klines)-1]
+
+	//bearish candle with low far below the ladder and higher candle after it
+	low := candlescommon.KLine{OpenTime: last.OpenTime + 60000, CloseTime: last.CloseTime + 60000, OpenPrice: last.ClosePrice, HighPrice: last.ClosePrice, LowPrice: last.ClosePrice / 1e6, ClosePrice: last.ClosePrice * 0.9, Closed: true}
+	next := candlescommon.KLine{OpenTime: low.OpenTime + 60000, CloseTime: low.CloseTime + 60000, OpenPrice: low.ClosePrice, HighPrice: low.ClosePrice * 1.1, LowPrice: low.ClosePrice, ClosePrice: low.ClosePrice * 1.05, Closed: true}
+
+	sequence, position, accepted := tracker.Evaluate(low)
+
+	if position != indicators.LadderBelow || accepted || sequence.LadderPosition != indicators.LadderBelow.String() || sequence.Timestamp != low.OpenTime {
+		t.Fatalf("low below the ladder evaluated to %+v, %v, %v", sequence, position, accepted)
+	}
+
+	if pushes := append(tracker.Add(low), tracker.Confirm(next)...); len(pushes) != 0 {
+		t.Fatalf("low below the ladder pushed %+v", pushes)
+	}
+
+	if after := tracker.Sequences(); !reflect.DeepEqual(after, before) {
+		t.Fatalf("stack changed by low below the ladder %+v\nbefore %+v", after, before)
+	}
+}
+
+// sequences of candles split into batches like GetSequncesWithUpdate are the same as sequences of one tracker
+func TestSequenceTrackerBatchesMatchSinglePass(t *testing.T) {
+
+	for _, pivots := range []string{"fractal:2:2", "fractal:3:4", "zigzag:3"} {
+
+		options := SequenceOptions{Pivots: pivots}
+
+		for seed := int64(1); seed <= 5; seed++ {
+
+			klines := randomKLines(seed, 1300)
+
+			newTracker := func() *SequenceTracker {
+
+				tracker, err := options.NewTracker(indicators.NewRSIMultiplePeriods(indicators.DefaultMaxPeriod), DefaultCentralRSI)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return tracker
+			}
+
+			single := newTracker()
+			single.Prime(klines[:500])
+
+			for _, candle := range klines[500 : len(klines)-1] {
+				single.Add(candle)
+			}
+
+			single.Confirm(klines[len(klines)-1])
+
+			//older batch is confirmed by the first candles of newer batch
+			newer := newTracker()
+			newer.Prime(klines[:900])
+
+			for _, candle := range klines[900 : len(klines)-1] {
+				newer.Add(candle)
+			}
+
+			newer.Confirm(klines[len(klines)-1])
+
+			older := newTracker()
+			older.Prime(klines[:500])
+
+			for _, candle := range klines[500:900] {
+				older.Add(candle)
+			}
+
+			older.Confirm(klines[900 : 900+older.maxDelay()]...)
+
+			batches := list.New()
+			MergeSequences(batches, newer.List())
+			MergeSequences(batches, older.List())
+
+			if found, expected := listSequences(batches), single.Sequences(); !reflect.DeepEqual(found, expected) {
+				t.Fatalf("%s seed %d: batches %+v\nsingle pass %+v", pivots, seed, found, expected)
+			}
+
+			//snapshot saved after candle end-1 is continued by the next request, lows of the last saved candles
+			//are on top of the stack a few candles later
+			for end := 890; end < 900; end++ {
+
+				last := end + 10
+
+				reference := newTracker()
+				reference.Prime(klines[:500])
+
+				for _, candle := range klines[500:last] {
+					reference.Add(candle)
+				}
+
+				reference.Confirm(klines[last])
+
+				saved := newTracker()
+				saved.Prime(klines[:500])
+
+				for _, candle := range klines[500 : end-1] {
+					saved.Add(candle)
+				}
+
+				saved.Confirm(klines[end-1])
+
+				update := newTracker()
+				update.Resume(klines[:end], saved.Sequences()[0].Timestamp)
+
+				for _, candle := range klines[end:last] {
+					update.Add(candle)
+				}
+
+				update.Confirm(klines[last])
+
+				snapshots := update.List()
+				MergeSequences(snapshots, saved.List())
+
+				if found, expected := listSequences(snapshots), reference.Sequences(); !reflect.DeepEqual(found, expected) {
+					t.Fatalf("%s seed %d: snapshot till %d continued %+v\nsingle pass %+v", pivots, seed, end, found, expected)
+				}
+			}
+		}
+	}
+}
+
+func listSequences(sequences *list.List) []SequenceValue {
+
+	result := make([]SequenceValue, 0, sequences.Len())
+
+	for e := sequences.Front(); e != nil; e = e.Next() {
+		result = append(result, e.Value.(SequenceValue))
+	}
+
+	return result
+}
@@ -0,0 +1,162 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/NERON/tran/database"
+	"github.com/lib/pq"
+	"log"
+	"time"
+)
+
+// RetentionTier keeps one snapshot per Spacing among snapshots older than Age
+type RetentionTier struct {
+	Age     time.Duration
+	Spacing time.Duration
+}
+
+// SnapshotRetention is tiers ordered by age, snapshots younger than the first tier are all kept
+type SnapshotRetention []RetentionTier
+
+// DefaultSnapshotRetention keeps all snapshots of the last day, hourly snapshots for a week and daily snapshots after
+var DefaultSnapshotRetention = SnapshotRetention{
+	{Age: 24 * time.Hour, Spacing: time.Hour},
+	{Age: 7 * 24 * time.Hour, Spacing: 24 * time.Hour},
+}
+
+// Compact removes snapshots that aren't the earliest in their spacing interval of tier.
+// The earliest and the latest snapshot of every symbol, interval, central RSI and options are always kept,
+// so any timestamp after the first snapshot is rebuilt from the nearest kept snapshot before it,
+// which is not older than spacing of tier of the timestamp
+func (retention SnapshotRetention) Compact(now time.Time) (int64, error) {
+
+	rows, err := database.DatabaseManager.Query(`SELECT id, symbol, "interval", "centralRSI", options, "lastUpdate" FROM public.tran_sequence_snapshots ORDER BY symbol, "interval", "centralRSI", options, "lastUpdate";`)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	ids := make([]int64, 0)
+	lastUpdates := make([]int64, 0)
+	partition := ""
+
+	removed := int64(0)
+
+	for rows.Next() {
+
+		var id, lastUpdate int64
+		var symbol, interval, options string
+		var centralRSI uint
+
+		err = rows.Scan(&id, &symbol, &interval, &centralRSI, &options, &lastUpdate)
+
+		if err != nil {
+			return removed, err
+		}
+
+		if key := fmt.Sprintf("%s_%s_%d_%s", symbol, interval, centralRSI, options); key != partition {
+
+			count, err := retention.remove(ids, lastUpdates, now)
+
+			if err != nil {
+				return removed, err
+			}
+
+			removed += count
+
+			ids, lastUpdates, partition = ids[:0], lastUpdates[:0], key
+		}
+
+		ids = append(ids, id)
+		lastUpdates = append(lastUpdates, lastUpdate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return removed, err
+	}
+
+	count, err := retention.remove(ids, lastUpdates, now)
+
+	return removed + count, err
+}
+
+// remove deletes snapshots of one partition that removable returns
+func (retention SnapshotRetention) remove(ids []int64, lastUpdates []int64, now time.Time) (int64, error) {
+
+	removedIDs := make([]int64, 0)
+
+	for _, idx := range retention.removable(lastUpdates, now) {
+		removedIDs = append(removedIDs, ids[idx])
+	}
+
+	if len(removedIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := database.DatabaseManager.Exec(`DELETE FROM public.tran_sequence_snapshots WHERE id = ANY($1);`, pq.Array(removedIDs))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// removable returns indexes of snapshots that are removed from ascending lastUpdates of one partition.
+// Spacing intervals are counted inside tier, so interval that straddles tier boundary keeps the earliest snapshot on both sides
+func (retention SnapshotRetention) removable(lastUpdates []int64, now time.Time) []int {
+
+	removed := make([]int, 0)
+
+	for idx := 1; idx < len(lastUpdates)-1; idx++ {
+
+		tier, ok := retention.tier(lastUpdates[idx], now)
+
+		if !ok {
+			continue
+		}
+
+		previousTier, _ := retention.tier(lastUpdates[idx-1], now)
+		spacing := retention[tier].Spacing.Milliseconds()
+
+		if previousTier == tier && lastUpdates[idx-1]/spacing == lastUpdates[idx]/spacing {
+			removed = append(removed, idx)
+		}
+	}
+
+	return removed
+}
+
+// tier returns the oldest tier which age lastUpdate reached, false if snapshot is younger than the first tier
+func (retention SnapshotRetention) tier(lastUpdate int64, now time.Time) (int, bool) {
+
+	for idx := len(retention) - 1; idx >= 0; idx-- {
+
+		if lastUpdate < now.Add(-retention[idx].Age).UnixMilli() {
+			return idx, true
+		}
+	}
+
+	return -1, false
+}
+
+// StartSnapshotRetention compacts snapshots every period in background
+func StartSnapshotRetention(retention SnapshotRetention, period time.Duration) {
+
+	go func() {
+
+		for {
+
+			removed, err := retention.Compact(time.Now())
+
+			if err != nil {
+				log.Println("Snapshot retention error: ", err.Error())
+			} else {
+				log.Println("Snapshot retention removed: ", removed)
+			}
+
+			time.Sleep(period)
+		}
+	}()
+}
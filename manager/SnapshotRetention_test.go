@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"testing"
+	"time"
+)
+
+// keptAfterRemoval returns lastUpdates without removed indexes
+func keptAfterRemoval(lastUpdates []int64, removed []int) []int64 {
+
+	kept := make([]int64, 0, len(lastUpdates))
+	removedSet := make(map[int]bool)
+
+	for _, idx := range removed {
+		removedSet[idx] = true
+	}
+
+	for idx, lastUpdate := range lastUpdates {
+
+		if !removedSet[idx] {
+			kept = append(kept, lastUpdate)
+		}
+	}
+
+	return kept
+}
+
+func TestCompactKeepsNearestSnapshot(t *testing.T) {
+
+	//tier boundaries aren't aligned to hours and days, so intervals straddle them
+	now := time.Date(2026, 3, 10, 10, 37, 0, 0, time.UTC)
+
+	lastUpdates := make([]int64, 0)
+
+	for snapshot := now.Add(-30 * 24 * time.Hour).Truncate(time.Hour); snapshot.Before(now); snapshot = snapshot.Add(15 * time.Minute) {
+		lastUpdates = append(lastUpdates, snapshot.UnixMilli())
+	}
+
+	retention := DefaultSnapshotRetention
+	kept := keptAfterRemoval(lastUpdates, retention.removable(lastUpdates, now))
+
+	if kept[0] != lastUpdates[0] || kept[len(kept)-1] != lastUpdates[len(lastUpdates)-1] {
+		t.Fatalf("the earliest or the latest snapshot is removed")
+	}
+
+	//every snapshot has kept snapshot before it not older than spacing of its tier
+	for _, lastUpdate := range lastUpdates {
+
+		nearest := int64(-1)
+
+		for _, keptUpdate := range kept {
+
+			if keptUpdate <= lastUpdate {
+				nearest = keptUpdate
+			}
+		}
+
+		spacing := int64(0)
+
+		if tier, ok := retention.tier(lastUpdate, now); ok {
+			spacing = retention[tier].Spacing.Milliseconds()
+		}
+
+		if nearest < 0 || lastUpdate-nearest > spacing {
+			t.Fatalf("snapshot %s has nearest kept snapshot %s", time.UnixMilli(lastUpdate).UTC(), time.UnixMilli(nearest).UTC())
+		}
+	}
+
+	//hour 10:00 straddles the weekly boundary at 10:37, the first hourly snapshot after boundary is kept
+	boundary := time.Date(2026, 3, 3, 10, 45, 0, 0, time.UTC).UnixMilli()
+	found := false
+
+	for _, keptUpdate := range kept {
+		found = found || keptUpdate == boundary
+	}
+
+	if !found {
+		t.Fatalf("the first snapshot after tier boundary is removed")
+	}
+
+	//compacting again removes nothing
+	if removed := retention.removable(kept, now); len(removed) != 0 {
+		t.Fatalf("the second compaction removed %d snapshots", len(removed))
+	}
+}
@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"errors"
+	"github.com/NERON/tran/candlescommon"
+	"math"
+	"strings"
+	"sync"
+)
+
+var errSyntheticEmpty = errors.New("synthetic symbol has no common candles")
+
+type SyntheticSymbol struct {
+	Name      string
+	First     string
+	Second    string
+	Operation string
+}
+
+var syntheticSymbols = make(map[string]SyntheticSymbol)
+var syntheticMu = &sync.RWMutex{}
+
+// RegisterSyntheticSymbol defines name as product or ratio of two real symbols
+func RegisterSyntheticSymbol(name string, first string, second string, operation string) {
+
+	syntheticMu.Lock()
+	syntheticSymbols[name] = SyntheticSymbol{Name: name, First: first, Second: second, Operation: operation}
+	syntheticMu.Unlock()
+}
+
+// GetSyntheticSymbol finds registered synthetic symbol, inline names like MFTETH*ETHUSDT are also accepted
+func GetSyntheticSymbol(name string) (SyntheticSymbol, bool) {
+
+	syntheticMu.RLock()
+	synthetic, ok := syntheticSymbols[name]
+	syntheticMu.RUnlock()
+
+	if ok {
+		return synthetic, true
+	}
+
+	for _, operation := range []string{candlescommon.SyntheticProduct, candlescommon.SyntheticRatio} {
+
+		parts := strings.Split(name, operation)
+
+		if len(parts) == 2 && len(parts[0]) > 0 && len(parts[1]) > 0 {
+			return SyntheticSymbol{Name: name, First: parts[0], Second: parts[1], Operation: operation}, true
+		}
+	}
+
+	return SyntheticSymbol{}, false
+}
+
+func (s SyntheticSymbol) combine(first []candlescommon.KLine, second []candlescommon.KLine, limit int) ([]candlescommon.KLine, error) {
+
+	klines := candlescommon.CombineKlines(s.Name, first, second, s.Operation)
+
+	if len(klines) == 0 {
+		return nil, errSyntheticEmpty
+	}
+
+	if len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+
+	return klines, nil
+}
+
+func (s SyntheticSymbol) GetLastKLines(interval candlescommon.Interval, limit int) ([]candlescommon.KLine, error) {
+
+	first, err := GetLastKLines(s.First, interval, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := GetLastKLines(s.Second, interval, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.combine(first, second, limit)
+}
+
+func (s SyntheticSymbol) GetLastKLinesFromTimestamp(interval candlescommon.Interval, timestamp uint64, limit int) ([]candlescommon.KLine, error) {
+
+	first, err := GetLastKLinesFromTimestamp(s.First, interval, timestamp, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := GetLastKLinesFromTimestamp(s.Second, interval, timestamp, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	//no data before timestamp isn't error for this function
+	if len(first) == 0 || len(second) == 0 {
+		return make([]candlescommon.KLine, 0), nil
+	}
+
+	return s.combine(first, second, limit)
+}
+
+func (s SyntheticSymbol) GetKLinesInRange(interval candlescommon.Interval, fromTimestamp uint64, endTimestamp uint64, limit int) ([]candlescommon.KLine, error) {
+
+	first, err := GetKLinesInRange(s.First, interval, fromTimestamp, endTimestamp, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := GetKLinesInRange(s.Second, interval, fromTimestamp, endTimestamp, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	klines := candlescommon.CombineKlines(s.Name, first, second, s.Operation)
+
+	if len(klines) > limit {
+		klines = klines[:limit]
+	}
+
+	return klines, nil
+}
+
+func (s SyntheticSymbol) GetFirstKLines(interval candlescommon.Interval, limit int) ([]candlescommon.KLine, error) {
+
+	first, err := GetFirstKLines(s.First, interval, limit)
+
+	if err != nil || len(first) == 0 {
+		return first, err
+	}
+
+	second, err := GetFirstKLines(s.Second, interval, limit)
+
+	if err != nil || len(second) == 0 {
+		return second, err
+	}
+
+	//symbols can be listed at different time, so start from the latest listing
+	if first[0].OpenTime != second[0].OpenTime {
+
+		startTime := first[0].OpenTime
+
+		if second[0].OpenTime > startTime {
+			startTime = second[0].OpenTime
+		}
+
+		return s.GetKLinesInRange(interval, startTime-1, math.MaxUint64, limit)
+	}
+
+	klines := candlescommon.CombineKlines(s.Name, first, second, s.Operation)
+
+	if len(klines) > limit {
+		klines = klines[:limit]
+	}
+
+	return klines, nil
+}
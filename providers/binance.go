@@ -28,6 +28,7 @@ type GetKlineRange struct {
 func GetSupportedTimeframes() map[string][]uint {
 
 	return map[string][]uint{
+		"s": {5, 15, 30},
 		"m": {1},
 		"h": {1},
 		"d": {1, 3},
@@ -111,6 +112,11 @@ func GetKlinesNew(symbol string, interval string, ranges GetKlineRange) ([]candl
 }
 func getKline(symbol string, interval string, ranges GetKlineRange) ([]candlescommon.KLine, error) {
 
+	//binance hasn't seconds klines, build them from trades
+	if interval[len(interval)-1] == 's' {
+		return getAggKline(symbol, interval, ranges)
+	}
+
 	limiter.Wait(context.Background())
 
 	urlS := fmt.Sprintf("https://api.binance.com/api/v1/klines?symbol=%s&interval=%s&limit=1000", symbol, interval)
@@ -0,0 +1,322 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/NERON/tran/candlescommon"
+	"golang.org/x/time/rate"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"time"
+)
+
+// max time window that binance accept for aggTrades by time
+const aggTradesMaxWindow = uint64(3600 * 1000)
+
+// aggTradesMaxLookback is how far back trade is searched before klines window without trades,
+// symbol without trades for longer is treated as not traded before
+const aggTradesMaxLookback = 30 * 24 * aggTradesMaxWindow
+
+// page of klines from trades can take dozens of aggTrades pages on liquid symbols, they are limited apart from klines
+var aggTradesLimiter = rate.NewLimiter(rate.Limit(5), 1)
+
+type aggTradeData struct {
+	AggID        uint64  `json:"a"`
+	Price        float64 `json:"p,string"`
+	Quantity     float64 `json:"q,string"`
+	Timestamp    uint64  `json:"T"`
+	IsBuyerMaker bool    `json:"m"`
+}
+
+type AggTradesRange struct {
+	FromID    uint64
+	StartTime uint64
+	EndTime   uint64
+}
+
+// GetAggTrades fetches one page of aggregated trades, by id if FromID set, else by time
+func GetAggTrades(symbol string, ranges AggTradesRange) ([]candlescommon.Trade, error) {
+
+	aggTradesLimiter.Wait(context.Background())
+	limiter.Wait(context.Background())
+
+	urlS := fmt.Sprintf("https://api.binance.com/api/v3/aggTrades?symbol=%s&limit=1000", symbol)
+
+	if ranges.FromID > 0 {
+
+		urlS = fmt.Sprintf(urlS+"&fromId=%d", ranges.FromID)
+
+	} else {
+
+		urlS = fmt.Sprintf(urlS+"&startTime=%d&endTime=%d", ranges.StartTime, ranges.EndTime)
+	}
+
+	resp, err := http.Get(urlS)
+
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var data []aggTradeData
+
+	err = json.Unmarshal(body, &data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	trades := make([]candlescommon.Trade, 0, len(data))
+
+	for _, agg := range data {
+
+		trades = append(trades, candlescommon.Trade{ID: agg.AggID, Price: agg.Price, Quantity: agg.Quantity, Timestamp: agg.Timestamp, IsBuyerMaker: agg.IsBuyerMaker})
+	}
+
+	return trades, nil
+}
+
+// GetAggTradesInRange fetches all aggregated trades between startTime and endTime, paging by id
+func GetAggTradesInRange(symbol string, startTime uint64, endTime uint64) ([]candlescommon.Trade, error) {
+
+	result := make([]candlescommon.Trade, 0)
+
+	ranges := AggTradesRange{StartTime: startTime}
+
+	for ranges.StartTime <= endTime {
+
+		ranges.EndTime = ranges.StartTime + aggTradesMaxWindow - 1
+
+		if ranges.EndTime > endTime {
+			ranges.EndTime = endTime
+		}
+
+		trades, err := GetAggTrades(symbol, ranges)
+
+		if err != nil {
+			return nil, err
+		}
+
+		//no trades in window, go to next one
+		if len(trades) == 0 {
+			ranges.StartTime = ranges.EndTime + 1
+			continue
+		}
+
+		//continue by id from first found trade
+		for len(trades) > 0 {
+
+			for _, trade := range trades {
+
+				if trade.Timestamp > endTime {
+					return result, nil
+				}
+
+				result = append(result, trade)
+			}
+
+			trades, err = GetAggTrades(symbol, AggTradesRange{FromID: trades[len(trades)-1].ID + 1})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		break
+	}
+
+	return result, nil
+}
+
+// nearestTradeTime returns time of the latest trade before timestamp if backward, else time of the first trade
+// from timestamp to now, false if there is no such trade
+func nearestTradeTime(symbol string, timestamp uint64, backward bool) (uint64, bool, error) {
+
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+
+	if !backward {
+
+		for start := timestamp; start <= now; start += aggTradesMaxWindow {
+
+			trades, err := GetAggTrades(symbol, AggTradesRange{StartTime: start, EndTime: start + aggTradesMaxWindow - 1})
+
+			if err != nil {
+				return 0, false, err
+			}
+
+			if len(trades) > 0 {
+				return trades[0].Timestamp, true, nil
+			}
+		}
+
+		return 0, false, nil
+	}
+
+	for end := timestamp; end > 0 && timestamp-end < aggTradesMaxLookback; {
+
+		start := uint64(0)
+
+		if end > aggTradesMaxWindow {
+			start = end - aggTradesMaxWindow
+		}
+
+		trades, err := GetAggTrades(symbol, AggTradesRange{StartTime: start, EndTime: end - 1})
+
+		if err != nil {
+			return 0, false, err
+		}
+
+		if len(trades) > 0 {
+
+			//page shows the first trades of window, the last one is found by id
+			trades, err = GetAggTradesInRange(symbol, trades[0].Timestamp, end-1)
+
+			if err != nil {
+				return 0, false, err
+			}
+
+			return trades[len(trades)-1].Timestamp, true, nil
+		}
+
+		end = start
+	}
+
+	return 0, false, nil
+}
+
+// getAggKline builds klines from aggregated trades, result has the same format as getKline
+func getAggKline(symbol string, interval string, ranges GetKlineRange) ([]candlescommon.KLine, error) {
+
+	in := candlescommon.IntervalFromStr(interval)
+	duration := in.Milliseconds()
+
+	if duration == 0 {
+		return nil, fmt.Errorf("wrong interval %s", interval)
+	}
+
+	now := uint64(time.Now().UnixNano() / int64(time.Millisecond))
+
+	var fromOpen, toOpen uint64
+
+	if ranges.Direction == 0 {
+
+		toOpen = ranges.FromTimestamp / duration * duration
+
+		if toOpen > now {
+			toOpen = now / duration * duration
+		}
+
+		fromOpen = windowStart(toOpen, duration)
+
+	} else if ranges.FromTimestamp > 0 {
+
+		fromOpen = ranges.FromTimestamp / duration * duration
+		toOpen = fromOpen + 999*duration
+
+	} else {
+
+		toOpen = now / duration * duration
+		fromOpen = windowStart(toOpen, duration)
+	}
+
+	if toOpen > now {
+		toOpen = now / duration * duration
+	}
+
+	trades, err := GetAggTradesInRange(symbol, fromOpen, toOpen+duration-1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	//empty window isn't the end of data, window is moved to the nearest trade in direction of loading
+	if len(trades) == 0 {
+
+		backward := ranges.Direction == 0 || ranges.FromTimestamp == 0
+		timestamp := toOpen + duration
+
+		if backward {
+			timestamp = fromOpen
+		}
+
+		tradeTime, found, err := nearestTradeTime(symbol, timestamp, backward)
+
+		if err != nil || !found {
+			return make([]candlescommon.KLine, 0), err
+		}
+
+		if backward {
+
+			toOpen = tradeTime / duration * duration
+
+			//the newest candle of loading back is dropped as already saved one, so the candle of trade is kept
+			if ranges.Direction == 0 {
+				toOpen += duration
+			}
+
+			fromOpen = windowStart(toOpen, duration)
+
+		} else {
+
+			fromOpen = tradeTime / duration * duration
+			toOpen = fromOpen + 999*duration
+
+			if toOpen > now {
+				toOpen = now / duration * duration
+			}
+		}
+
+		trades, err = GetAggTradesInRange(symbol, fromOpen, toOpen+duration-1)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	klines := candlescommon.TradesToKlines(symbol, trades, in, fromOpen, toOpen)
+
+	result := make([]candlescommon.KLine, 0, len(klines))
+
+	for j := len(klines) - 1; j >= 0; j-- {
+
+		kline := klines[j]
+		kline.PrevCloseCandleTimestamp = 0
+
+		if len(result) > 0 {
+
+			result[len(result)-1].PrevCloseCandleTimestamp = kline.CloseTime
+		}
+
+		result = append(result, kline)
+	}
+
+	//candle that contains current time isn't finished
+	if len(result) > 0 && result[0].CloseTime >= now {
+		result[0].Closed = false
+	}
+
+	//the first aggregated trade of symbol has id 0, there are no candles before it
+	if ranges.Direction == 0 && len(result) > 0 && (fromOpen == 0 || trades[0].ID == 0) {
+		result[len(result)-1].PrevCloseCandleTimestamp = math.MaxUint64
+	}
+
+	return result, nil
+}
+
+// windowStart returns open time of the first of 1000 klines that end with toOpen, it isn't earlier than 0
+func windowStart(toOpen uint64, duration uint64) uint64 {
+
+	if toOpen < 999*duration {
+		return 0
+	}
+
+	return toOpen - 999*duration
+}